@@ -0,0 +1,147 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+)
+
+// AuthorizationDetail is an authorization details object of a rich
+// authorization request, describing fine-grained permissions of a type, see:
+// http://tools.ietf.org/html/rfc9396#section-2
+type AuthorizationDetail map[string]interface{}
+
+// Type returns the "type" field of the authorization detail.
+func (d AuthorizationDetail) Type() string {
+	s, _ := d["type"].(string)
+	return s
+}
+
+// AuthorizationDetailValidator validates the authorization details objects
+// of a type.
+type AuthorizationDetailValidator interface {
+	// ValidateAuthorizationDetail validates the authorization detail
+	// requested by client, returning an error if the detail is invalid or
+	// not allowed for client.
+	ValidateAuthorizationDetail(client Client, detail AuthorizationDetail) error
+}
+
+// parseAuthorizationDetails parses the "authorization_details" request
+// parameter, a JSON array of objects with a "type" field. Returns nil if the
+// parameter is blank.
+func parseAuthorizationDetails(details string) ([]AuthorizationDetail, error) {
+	if details == "" {
+		return nil, nil
+	}
+	var ret []AuthorizationDetail
+	if err := json.Unmarshal([]byte(details), &ret); err != nil {
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("authorization details must not be empty")
+	}
+	for _, d := range ret {
+		if d.Type() == "" {
+			return nil, errors.New("authorization detail type required")
+		}
+	}
+	return ret, nil
+}
+
+// validateAuthorizationDetails validates the authorization details requested
+// by client with the validators of the server AuthorizationDetailTypes.
+func (s *Server) validateAuthorizationDetails(client Client, details []AuthorizationDetail) error {
+	for _, d := range details {
+		v, ok := s.AuthorizationDetailTypes[d.Type()]
+		if !ok {
+			return errors.New("unsupported authorization detail type: " + d.Type())
+		}
+		if err := v.ValidateAuthorizationDetail(client, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// authorizationDetailTypes returns the supported authorization detail types,
+// sorted.
+func (s *Server) authorizationDetailTypes() []string {
+	var ret []string
+	for typ := range s.AuthorizationDetailTypes {
+		ret = append(ret, typ)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// containsAuthorizationDetails determines if all of b are in a.
+func containsAuthorizationDetails(a, b []AuthorizationDetail) bool {
+	for _, d := range b {
+		found := false
+		for _, e := range a {
+			if reflect.DeepEqual(d, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// handleAuthorizationDetails sets the authorization details of the token.
+// Details can only be requested with new grants for client credentials;
+// with the other grants, they must be within the details of the grant, and
+// default to them, see:
+// http://tools.ietf.org/html/rfc9396#section-6
+func (s *Server) handleAuthorizationDetails(w *Response, r *http.Request, ret *TokenRequest) bool {
+	details, err := parseAuthorizationDetails(r.Form.Get("authorization_details"))
+	if err != nil {
+		w.SetError(ErrInvalidAuthorizationDetails)
+		w.InternalError = err
+		return false
+	}
+
+	if ret.GrantType == ClientCredentialsGrant {
+		if err = s.validateAuthorizationDetails(ret.Client, details); err != nil {
+			w.SetError(ErrInvalidAuthorizationDetails)
+			w.InternalError = err
+			return false
+		}
+		ret.AuthorizationDetails = details
+		return true
+	}
+
+	granted := ret.grantedAuthorizationDetails()
+	if !containsAuthorizationDetails(granted, details) {
+		w.SetError(ErrInvalidAuthorizationDetails)
+		w.InternalError = errors.New("the requested authorization details must not include any details not originally granted")
+		return false
+	}
+	ret.AuthorizationDetails = details
+	if len(ret.AuthorizationDetails) == 0 {
+		ret.AuthorizationDetails = granted
+	}
+	return true
+}
+
+// grantedAuthorizationDetails returns the authorization details of the grant
+// of the token request, those of the refreshed grant, of the authorization,
+// or of the device or backchannel authorization. Nil for other grants.
+func (ar *TokenRequest) grantedAuthorizationDetails() []AuthorizationDetail {
+	switch {
+	case ar.AccessGrant != nil:
+		return ar.AccessGrant.GrantedAuthorizationDetails
+	case ar.AuthorizeData != nil:
+		return ar.AuthorizeData.AuthorizationDetails
+	case ar.DeviceAuthorization != nil:
+		return ar.DeviceAuthorization.AuthorizationDetails
+	case ar.BackchannelAuthentication != nil:
+		return ar.BackchannelAuthentication.AuthorizationDetails
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testPaymentValidator struct{}
+
+func (testPaymentValidator) ValidateAuthorizationDetail(client Client, detail AuthorizationDetail) error {
+	if _, ok := detail["instructedAmount"].(map[string]interface{}); !ok {
+		return errors.New("instructedAmount required")
+	}
+	return nil
+}
+
+const testPaymentDetails = `[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"45.00"},"creditorAccount":{"iban":"DE02100100109307118603"}}]`
+
+// testAuthorizationDetailTypes are the supported authorization detail types.
+var testAuthorizationDetailTypes = map[string]AuthorizationDetailValidator{
+	"payment_initiation": testPaymentValidator{},
+}
+
+func TestAuthorizationDetails(t *testing.T) {
+	server := newTestServer(t, AuthorizationCodeGrant)
+	server.AuthorizationDetailTypes = testAuthorizationDetailTypes
+
+	// authorize payment
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"1234"},
+		"authorization_details": {testPaymentDetails},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		if len(ar.AuthorizationDetails) != 1 || ar.AuthorizationDetails[0].Type() != "payment_initiation" {
+			t.Fatalf("Unexpected authorization details: %v", ar.AuthorizationDetails)
+		}
+		ar.Authorized = true
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	// exchange code
+	resp = doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {resp.Output["code"].(string)},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	details, ok := resp.Output["authorization_details"].([]AuthorizationDetail)
+	if !ok || len(details) != 1 {
+		t.Fatalf("Unexpected authorization details: %v", resp.Output["authorization_details"])
+	}
+	amount := details[0]["instructedAmount"].(map[string]interface{})
+	if amount["currency"] != "EUR" || amount["amount"] != "45.00" {
+		t.Fatalf("Unexpected amount: %v", amount)
+	}
+
+	// introspect token
+	token := resp.Output["access_token"].(string)
+	resp = server.NewResponse()
+	if req, err = http.NewRequest("POST", "http://localhost:14000/introspect", nil); err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{"token": {token}}
+	req.PostForm = url.Values{}
+	if ir := server.HandleIntrospectionRequest(resp, req); ir != nil {
+		server.FinishIntrospectionRequest(resp, req, ir)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	if details, ok := resp.Output["authorization_details"].([]AuthorizationDetail); !ok || len(details) != 1 {
+		t.Fatalf("Unexpected introspection authorization details: %v", resp.Output["authorization_details"])
+	}
+}
+
+func TestAuthorizationDetailsInvalid(t *testing.T) {
+	server := newTestServer(t)
+	server.AuthorizationDetailTypes = testAuthorizationDetailTypes
+
+	var tests = []string{
+		`{"type":"payment_initiation"}`,
+		`[]`,
+		`[{"instructedAmount":{}}]`,
+		`[{"type":"account_information"}]`,
+		`[{"type":"payment_initiation"}]`,
+	}
+
+	for i, details := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/authorize", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"1234"},
+			"state":                 {"a"},
+			"authorization_details": {details},
+		}
+		if ar := server.HandleAuthRequest(resp, req); ar != nil {
+			t.Errorf("Authorization request should have failed (%d)", i)
+			continue
+		}
+		if resp.ErrorType != ErrInvalidAuthorizationDetails.Type || resp.Output["state"] != "a" {
+			t.Errorf("Expected error %q (%d), got: %q", ErrInvalidAuthorizationDetails.Type, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestAuthorizationDetailsTokenRequest(t *testing.T) {
+	server := newTestServer(t, ClientCredentialsGrant, AuthorizationCodeGrant, PasswordGrant)
+	server.AuthorizationDetailTypes = testAuthorizationDetailTypes
+
+	var tests = []struct {
+		grantType GrantType
+		details   string
+		err       string
+	}{
+		{ClientCredentialsGrant, testPaymentDetails, ""},
+		{ClientCredentialsGrant, `[{"type":"account_information"}]`, ErrInvalidAuthorizationDetails.Type},
+		// code 9999 was authorized without authorization details
+		{AuthorizationCodeGrant, testPaymentDetails, ErrInvalidAuthorizationDetails.Type},
+		// details can't be requested with new password grants
+		{PasswordGrant, testPaymentDetails, ErrInvalidAuthorizationDetails.Type},
+	}
+
+	for i, tt := range tests {
+		resp := doTestTokenRequest(t, server, "1234", url.Values{
+			"grant_type":            {string(tt.grantType)},
+			"code":                  {"9999"},
+			"username":              {"user"},
+			"password":              {"pass"},
+			"authorization_details": {tt.details},
+		})
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+			continue
+		}
+		if tt.err == "" && resp.Output["authorization_details"] == nil {
+			t.Errorf("Token response should include authorization details (%d)", i)
+		}
+	}
+}
+
+func TestAuthorizationDetailsDeviceCode(t *testing.T) {
+	server := newTestServer(t, DeviceCodeGrant)
+	server.AuthorizationDetailTypes = testAuthorizationDetailTypes
+	server.DeviceCodeGen = &TestingDeviceCodeGen{}
+
+	// device authorization
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/device_authorization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{"authorization_details": {testPaymentDetails}}
+	req.PostForm = url.Values{}
+	da := server.HandleDeviceAuthorizationRequest(resp, req)
+	if da == nil {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	server.FinishDeviceAuthorizationRequest(resp, req, da)
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	da.Status = DeviceApproved
+
+	var tests = []struct {
+		details string
+		err     string
+	}{
+		{`[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"99.00"}}]`, ErrInvalidAuthorizationDetails.Type},
+		{"", ""},
+	}
+
+	for i, tt := range tests {
+		resp = server.NewResponse()
+		req = newDeviceTokenRequest(t)
+		req.Form.Set("authorization_details", tt.details)
+		if tr := server.HandleTokenRequest(resp, req); tr != nil {
+			tr.Authorized = true
+			server.FinishTokenRequest(resp, req, tr)
+		}
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+			continue
+		}
+		if details, ok := resp.Output["authorization_details"].([]AuthorizationDetail); tt.err == "" && (!ok || len(details) != 1) {
+			t.Errorf("Unexpected authorization details (%d): %v", i, resp.Output["authorization_details"])
+		}
+	}
+
+	// details must be of supported types
+	resp = server.NewResponse()
+	req.Form = url.Values{"authorization_details": {`[{"type":"account_information"}]`}}
+	if da = server.HandleDeviceAuthorizationRequest(resp, req); da != nil || resp.ErrorType != ErrInvalidAuthorizationDetails.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrInvalidAuthorizationDetails.Type, resp.ErrorType)
+	}
+}
+
+func TestAuthorizationDetailsRefresh(t *testing.T) {
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.AuthorizationDetailTypes = testAuthorizationDetailTypes
+	storage := server.Storage.(*MemStorage)
+
+	var details []AuthorizationDetail
+	err := json.Unmarshal([]byte(`[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"45.00"}},{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"99.00"}}]`), &details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.SaveAuthorizeData(&AuthorizeData{
+		Client:               storage.Clients["1234"],
+		Code:                 "details",
+		ExpiresIn:            3600,
+		CreatedAt:            time.Now(),
+		RedirectURI:          "http://localhost:14000/appauth",
+		AuthorizationDetails: details,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// exchange code, then refresh narrowing the token to each detail
+	var tests = []struct {
+		form     url.Values
+		expected []AuthorizationDetail
+	}{
+		{url.Values{"grant_type": {string(AuthorizationCodeGrant)}, "code": {"details"}}, details},
+		{url.Values{"grant_type": {string(RefreshTokenGrant)}, "authorization_details": {`[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"45.00"}}]`}}, details[:1]},
+		{url.Values{"grant_type": {string(RefreshTokenGrant)}, "authorization_details": {`[{"type":"payment_initiation","instructedAmount":{"currency":"EUR","amount":"99.00"}}]`}}, details[1:]},
+		{url.Values{"grant_type": {string(RefreshTokenGrant)}}, details},
+	}
+
+	var refreshToken string
+	for i, tt := range tests {
+		tt.form.Set("refresh_token", refreshToken)
+		resp := doTestTokenRequest(t, server, "1234", tt.form)
+		if resp.IsError {
+			t.Fatalf("Should not be an error (%d): %v", i, resp.InternalError)
+		}
+
+		// token is narrowed, grant keeps the details
+		ag := storage.AccessGrants[resp.Output["access_token"].(string)]
+		if !reflect.DeepEqual(ag.AuthorizationDetails, tt.expected) {
+			t.Errorf("Unexpected token authorization details (%d): %v", i, ag.AuthorizationDetails)
+		}
+		if !reflect.DeepEqual(ag.GrantedAuthorizationDetails, details) {
+			t.Errorf("Unexpected granted authorization details (%d): %v", i, ag.GrantedAuthorizationDetails)
+		}
+		refreshToken = resp.Output["refresh_token"].(string)
+	}
+}
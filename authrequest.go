@@ -1,8 +1,10 @@
 package oauthlib
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -25,11 +27,58 @@ type AuthRequest struct {
 	// State is the passed state in the request.
 	State string
 
+	// CodeChallenge is the PKCE code challenge passed in the request.
+	CodeChallenge string
+
+	// CodeChallengeMethod is the PKCE code challenge method passed in the
+	// request.
+	CodeChallengeMethod string
+
+	// ResponseMode is the response mode of the authorization response.
+	ResponseMode string
+
+	// RequestURI is the request_uri of the pushed authorization request the
+	// parameters were loaded from. Blank if the request was not pushed.
+	RequestURI string
+
+	// RequestObject are the verified claims of the signed request object the
+	// parameters were taken from. Nil if the request was not signed.
+	RequestObject JWTClaims
+
+	// Resource are the requested resource URIs the tokens are restricted to.
+	// Can be blank
+	Resource []string
+
+	// AuthorizationDetails are the fine-grained permissions requested with
+	// the "authorization_details" parameter. Can be nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// Nonce is the OpenID Connect nonce passed in the request.
+	Nonce string
+
+	// Claims are the individual claims requested with the "claims"
+	// parameter. Can be nil
+	Claims *ClaimsRequest
+
+	// Subject is the resource owner that authorized the request. Set before
+	// calling FinishAuthRequest; required for OpenID Connect requests.
+	Subject string
+
+	// AuthTime is the time the resource owner authenticated. Defaults to the
+	// time of FinishAuthRequest if not set.
+	AuthTime time.Time
+
+	// SessionID is the id of the end-user's login session at the server.
+	// Set before calling FinishAuthRequest to add the client to the session,
+	// so that the end-user is logged out of the client when the session ends.
+	// Can be blank
+	SessionID string
+
 	// Authorized toggles if request is authorized
 	Authorized bool
 
 	// Expiration is the token expiration in seconds. Change if different from
-	// default. If type = "token", this expiration will be for the ACCESS
+	// default. If type has no "code", this expiration will be for the ACCESS
 	// token.
 	Expiration int32
 
@@ -60,6 +109,35 @@ type AuthorizeData struct {
 	// State is the passed state from request.
 	State string
 
+	// CodeChallenge is the PKCE code challenge from request.
+	CodeChallenge string
+
+	// CodeChallengeMethod is the PKCE code challenge method from request.
+	CodeChallengeMethod string
+
+	// Subject is the resource owner that authorized the request.
+	Subject string
+
+	// Audience are the resource URIs from request the tokens are restricted
+	// to. Can be blank
+	Audience []string
+
+	// AuthorizationDetails are the authorization details from request. Can
+	// be nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// Nonce is the OpenID Connect nonce from request.
+	Nonce string
+
+	// Claims are the individual claims requested. Can be nil
+	Claims *ClaimsRequest
+
+	// AuthTime is the time the resource owner authenticated.
+	AuthTime time.Time
+
+	// SessionID is the id of the end-user's login session. Can be blank
+	SessionID string
+
 	// CreatedAt is the creation time.
 	CreatedAt time.Time
 
@@ -89,6 +167,12 @@ type AuthorizeTokenGen interface {
 
 // HandleAuthRequest is the main http.HandlerFunc for handling
 // authorization requests.
+//
+// If the request has a "request_uri" parameter referencing a pushed
+// authorization request, the pushed parameters are used instead of the
+// request parameters. If the request has a signed request object, passed by
+// value ("request") or by reference ("request_uri"), the parameters are taken
+// from the verified request object.
 func (s *Server) HandleAuthRequest(w *Response, r *http.Request) *AuthRequest {
 	err := r.ParseForm()
 	if err != nil {
@@ -97,8 +181,51 @@ func (s *Server) HandleAuthRequest(w *Response, r *http.Request) *AuthRequest {
 		return nil
 	}
 
+	// resolve pushed authorization request
+	params, requestURI := r.Form, ""
+	if strings.HasPrefix(r.Form.Get("request_uri"), requestURIPrefix) {
+		requestURI = r.Form.Get("request_uri")
+		if params = s.loadPushedAuthorization(w, r); params == nil {
+			return nil
+		}
+	}
+
+	ret := s.handleAuthParams(w, r, params)
+	if ret == nil {
+		// errors redirected in the jwt response modes are signed as well
+		if w.ResponseType == REDIRECT && isJWTResponseMode(w.ResponseMode) {
+			if client, err := w.Storage.GetClient(params.Get("client_id")); err == nil && client != nil {
+				s.secureAuthResponse(w, client)
+			}
+		}
+		return nil
+	}
+	ret.RequestURI = requestURI
+
+	// check if the request must be pushed
+	if requestURI == "" && s.isPushedAuthorizationRequired(ret.Client) {
+		w.SetError(ErrInvalidRequest, ret.State)
+		w.InternalError = errors.New("pushed authorization request required")
+		s.secureAuthResponse(w, ret.Client)
+		return nil
+	}
+
+	return ret
+}
+
+// handleAuthParams validates the authorization request parameters, building
+// the AuthRequest.
+func (s *Server) handleAuthParams(w *Response, r *http.Request, params url.Values) *AuthRequest {
+	// resolve signed request object
+	var requestObject JWTClaims
+	if params.Get("request") != "" || params.Get("request_uri") != "" {
+		if params, requestObject = s.resolveRequestObject(w, params); params == nil {
+			return nil
+		}
+	}
+
 	// create the authorization request
-	unescapedURI, err := url.QueryUnescape(r.Form.Get("redirect_uri"))
+	unescapedURI, err := url.QueryUnescape(params.Get("redirect_uri"))
 	if err != nil {
 		w.SetError(ErrInvalidRequest)
 		w.InternalError = err
@@ -106,15 +233,17 @@ func (s *Server) HandleAuthRequest(w *Response, r *http.Request) *AuthRequest {
 	}
 
 	ret := &AuthRequest{
-		State:       r.Form.Get("state"),
-		Scope:       r.Form.Get("scope"),
+		State:       params.Get("state"),
+		Scope:       params.Get("scope"),
 		RedirectURI: unescapedURI,
 		Authorized:  false,
 		HttpRequest: r,
+
+		RequestObject: requestObject,
 	}
 
 	// must have a valid client
-	ret.Client, err = w.Storage.GetClient(r.Form.Get("client_id"))
+	ret.Client, err = w.Storage.GetClient(params.Get("client_id"))
 	if err != nil {
 		w.SetError(ErrServerError, ret.State)
 		w.InternalError = err
@@ -128,6 +257,11 @@ func (s *Server) HandleAuthRequest(w *Response, r *http.Request) *AuthRequest {
 		w.SetError(ErrUnauthorizedClient, ret.State)
 		return nil
 	}
+	if ret.RequestObject == nil && s.isSignedRequestObjectRequired(ret.Client) {
+		w.SetError(ErrInvalidRequest, ret.State)
+		w.InternalError = errors.New("signed request object required")
+		return nil
+	}
 
 	// check redirect uri, if there are multiple client redirect uri's
 	// don't set the uri
@@ -144,23 +278,98 @@ func (s *Server) HandleAuthRequest(w *Response, r *http.Request) *AuthRequest {
 	w.ResponseType = REDIRECT
 	w.URL = ret.RedirectURI
 
-	responseType := r.Form.Get("response_type")
-	if s.Config.isAuthRequestTypeAllowed(responseType) {
-		switch responseType {
-		case "code":
-			ret.Type = "code"
-			ret.Expiration = s.Config.AuthorizationExpiration
-		case "token":
-			ret.Type = "token"
-			ret.Expiration = s.Config.AccessExpiration
-		default:
-			// FIXME -- this should be an error!
+	// resolve the response mode, errors are returned in the default mode of
+	// the response type if the requested mode is not allowed
+	responseType := normalizeResponseType(params.Get("response_type"))
+	if ret.ResponseMode, err = s.Config.responseMode(params.Get("response_mode"), responseType); err != nil {
+		w.setResponseMode(defaultResponseMode(responseType))
+		w.SetError(ErrInvalidRequest, ret.State)
+		w.InternalError = err
+		return nil
+	}
+	w.setResponseMode(ret.ResponseMode)
+
+	if !s.Config.isAuthRequestTypeAllowed(responseType) {
+		w.SetError(ErrUnsupportedResponseType, ret.State)
+		return nil
+	}
+	ret.Type = responseType
+	ret.Expiration = s.Config.AccessExpiration
+
+	if responseTypeHas(ret.Type, "code") {
+		ret.Expiration = s.Config.AuthorizationExpiration
+
+		// check pkce code challenge
+		ret.CodeChallenge = params.Get("code_challenge")
+		ret.CodeChallengeMethod = params.Get("code_challenge_method")
+		if ret.CodeChallenge == "" {
+			if ret.CodeChallengeMethod != "" || s.Config.isPKCERequired(ret.Client) {
+				w.SetError(ErrInvalidRequest, ret.State)
+				w.InternalError = errors.New("code challenge required")
+				return nil
+			}
+		} else {
+			if ret.CodeChallengeMethod == "" {
+				ret.CodeChallengeMethod = PKCEMethodPlain
+			}
+			if !s.Config.isCodeChallengeMethodAllowed(ret.CodeChallengeMethod) {
+				w.SetError(ErrInvalidRequest, ret.State)
+				w.InternalError = errors.New("code challenge method not allowed")
+				return nil
+			}
+			if !validPKCEValue(ret.CodeChallenge) {
+				w.SetError(ErrInvalidRequest, ret.State)
+				w.InternalError = errors.New("invalid code challenge")
+				return nil
+			}
+		}
+	}
+
+	// resources must be known, see:
+	// http://tools.ietf.org/html/rfc8707#section-2.1
+	ret.Resource = params["resource"]
+	if err = s.validateResources(ret.Resource); err != nil {
+		w.SetError(ErrInvalidTarget, ret.State)
+		w.InternalError = err
+		return nil
+	}
+
+	// authorization details must be of supported types, see:
+	// http://tools.ietf.org/html/rfc9396#section-5
+	if ret.AuthorizationDetails, err = parseAuthorizationDetails(params.Get("authorization_details")); err == nil {
+		err = s.validateAuthorizationDetails(ret.Client, ret.AuthorizationDetails)
+	}
+	if err != nil {
+		w.SetError(ErrInvalidAuthorizationDetails, ret.State)
+		w.InternalError = err
+		return nil
+	}
+
+	// id tokens are only issued for openid requests, and a nonce is required
+	// if returned from the authorization endpoint, see:
+	// http://openid.net/specs/openid-connect-core-1_0.html#ImplicitAuthRequest
+	ret.Nonce = params.Get("nonce")
+	if responseTypeHas(ret.Type, "id_token") {
+		if !hasScope(ret.Scope, ScopeOpenID) {
+			w.SetError(ErrInvalidRequest, ret.State)
+			w.InternalError = errors.New("id_token response type requires openid scope")
+			return nil
 		}
-		return ret
+		if ret.Nonce == "" {
+			w.SetError(ErrInvalidRequest, ret.State)
+			w.InternalError = errors.New("nonce required")
+			return nil
+		}
+	}
+
+	// parse individually requested claims
+	if ret.Claims, err = parseClaimsRequest(params.Get("claims")); err != nil {
+		w.SetError(ErrInvalidRequest, ret.State)
+		w.InternalError = err
+		return nil
 	}
 
-	w.SetError(ErrUnsupportedResponseType, ret.State)
-	return nil
+	return ret
 }
 
 // FinishAuthRequest finishes the authorize request.
@@ -173,29 +382,39 @@ func (s *Server) FinishAuthRequest(w *Response, r *http.Request, ar *AuthRequest
 	// set redirect response
 	w.ResponseType = REDIRECT
 	w.URL = ar.RedirectURI
+	if ar.ResponseMode == "" {
+		ar.ResponseMode = defaultResponseMode(ar.Type)
+	}
+	w.setResponseMode(ar.ResponseMode)
+	defer s.secureAuthResponse(w, ar.Client)
+
+	// pushed authorization requests can only be used once
+	if ar.RequestURI != "" {
+		if ps, ok := w.Storage.(PushedAuthorizationStorage); ok {
+			if err := ps.RemovePushedAuthorization(ar.RequestURI); err != nil {
+				w.SetError(ErrServerError, ar.State)
+				w.InternalError = err
+				return
+			}
+		}
+	}
 
 	if ar.Authorized {
-		if ar.Type == "token" {
-			w.RedirectInFragment = true
+		if ar.AuthTime.IsZero() {
+			ar.AuthTime = s.Now()
+		}
 
-			// generate token directly
-			ret := &TokenRequest{
-				GrantType:       ImplicitGrant,
-				Code:            "",
-				Client:          ar.Client,
-				RedirectURI:     ar.RedirectURI,
-				Scope:           ar.Scope,
-				GenerateRefresh: false, // per the RFC, should NOT generate a refresh token in this case
-				Authorized:      true,
-				Expiration:      ar.Expiration,
-				UserData:        ar.UserData,
+		// add client to the end-user's session
+		if ar.SessionID != "" {
+			if err := s.addToSession(w.Storage, ar.SessionID, ar.Subject, ar.Client, ""); err != nil {
+				w.SetError(ErrServerError, ar.State)
+				w.InternalError = err
+				return
 			}
+		}
 
-			s.FinishTokenRequest(w, r, ret)
-			if ar.State != "" && w.InternalError == nil {
-				w.Output["state"] = ar.State
-			}
-		} else {
+		var code string
+		if responseTypeHas(ar.Type, "code") {
 			// generate authorization token
 			ret := &AuthorizeData{
 				Client:      ar.Client,
@@ -205,10 +424,21 @@ func (s *Server) FinishAuthRequest(w *Response, r *http.Request, ar *AuthRequest
 				State:       ar.State,
 				Scope:       ar.Scope,
 				UserData:    ar.UserData,
+				Subject:     ar.Subject,
+				Audience:    ar.Resource,
+				Nonce:       ar.Nonce,
+				AuthTime:    ar.AuthTime,
+				SessionID:   ar.SessionID,
+				Claims:      ar.Claims,
+
+				CodeChallenge:        ar.CodeChallenge,
+				CodeChallengeMethod:  ar.CodeChallengeMethod,
+				AuthorizationDetails: ar.AuthorizationDetails,
 			}
 
 			// generate token code
-			code, err := s.AuthorizeTokenGen.GenerateAuthorizeToken(ret)
+			var err error
+			code, err = s.AuthorizeTokenGen.GenerateAuthorizeToken(ret)
 			if err != nil {
 				w.SetError(ErrServerError, ar.State)
 				w.InternalError = err
@@ -227,6 +457,63 @@ func (s *Server) FinishAuthRequest(w *Response, r *http.Request, ar *AuthRequest
 			w.Output["code"] = ret.Code
 			w.Output["state"] = ret.State
 		}
+
+		if responseTypeHas(ar.Type, "token") {
+			// generate token directly
+			ret := &TokenRequest{
+				GrantType:       ImplicitGrant,
+				Code:            code,
+				Client:          ar.Client,
+				RedirectURI:     ar.RedirectURI,
+				Scope:           ar.Scope,
+				Subject:         ar.Subject,
+				Resource:        ar.Resource,
+				Audience:        ar.Resource,
+				GenerateRefresh: false, // per the RFC, should NOT generate a refresh token in this case
+				Authorized:      true,
+				Expiration:      s.Config.AccessExpiration,
+				UserData:        ar.UserData,
+
+				GenerateIDToken: responseTypeHas(ar.Type, "id_token"),
+				Nonce:           ar.Nonce,
+				AuthTime:        ar.AuthTime,
+				SessionID:       ar.SessionID,
+				Claims:          ar.Claims,
+
+				AuthorizationDetails: ar.AuthorizationDetails,
+			}
+			if code == "" {
+				ret.Expiration = ar.Expiration
+			}
+
+			s.FinishTokenRequest(w, r, ret)
+			if ar.State != "" && w.InternalError == nil {
+				w.Output["state"] = ar.State
+			}
+		} else if responseTypeHas(ar.Type, "id_token") {
+			// generate id token without access token
+			idToken, err := s.generateIDToken(&IDToken{
+				Client:    ar.Client,
+				Subject:   ar.Subject,
+				Nonce:     ar.Nonce,
+				AuthTime:  ar.AuthTime,
+				SessionID: ar.SessionID,
+				Code:      code,
+				Scope:     ar.Scope,
+				Claims:    ar.Claims,
+				ExpiresIn: s.Config.IDTokenExpiration,
+				CreatedAt: s.Now(),
+			})
+			if err != nil {
+				w.SetError(ErrServerError, ar.State)
+				w.InternalError = err
+				return
+			}
+			w.Output["id_token"] = idToken
+			if ar.State != "" {
+				w.Output["state"] = ar.State
+			}
+		}
 	} else {
 		// redirect with error
 		w.SetError(ErrAccessDenied, ar.State)
@@ -0,0 +1,573 @@
+package oauthlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Backchannel token delivery modes, see:
+// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.5
+const (
+	// BackchannelModePoll is the mode where the client polls the token
+	// endpoint.
+	BackchannelModePoll = "poll"
+
+	// BackchannelModePing is the mode where the client is notified at its
+	// client notification endpoint, and then requests the tokens from the
+	// token endpoint.
+	BackchannelModePing = "ping"
+
+	// BackchannelModePush is the mode where the tokens are sent to the client
+	// notification endpoint.
+	BackchannelModePush = "push"
+)
+
+// BackchannelAuthenticationStatus is the status of a backchannel
+// authentication.
+type BackchannelAuthenticationStatus int
+
+const (
+	// BackchannelPending is the status of a backchannel authentication
+	// awaiting the end-user decision.
+	BackchannelPending BackchannelAuthenticationStatus = iota
+
+	// BackchannelApproved is the status of a backchannel authentication
+	// approved by the end-user.
+	BackchannelApproved
+
+	// BackchannelDenied is the status of a backchannel authentication denied
+	// by the end-user.
+	BackchannelDenied
+)
+
+// BackchannelAuthentication is a client initiated backchannel authentication
+// (CIBA) request, where the end-user authenticates and approves the request
+// on their authentication device instead of being redirected.
+//
+// See http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html
+type BackchannelAuthentication struct {
+	// Client information.
+	Client Client
+
+	// AuthReqID is the authentication request id.
+	AuthReqID string
+
+	// Scope is the requested scope.
+	Scope string
+
+	// AuthorizationDetails are the requested authorization details. Can be
+	// nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// LoginHint is the login_hint identifying the end-user. Can be blank
+	LoginHint string
+
+	// LoginHintToken is the login_hint_token identifying the end-user. Can
+	// be blank
+	LoginHintToken string
+
+	// IDTokenHint is the id_token_hint identifying the end-user, an ID token
+	// previously issued by the server. Can be blank
+	IDTokenHint string
+
+	// Subject is the end-user the request is for. Set from the IDTokenHint,
+	// otherwise must be resolved from the LoginHint or LoginHintToken before
+	// calling FinishBackchannelAuthenticationRequest.
+	Subject string
+
+	// BindingMessage is the message shown on both the consumption and
+	// authentication devices, binding the request to the end-user's
+	// session. Can be blank
+	BindingMessage string
+
+	// UserCode is the secret code the end-user provided to the client. Can
+	// be blank
+	UserCode string
+
+	// ACRValues are the requested authentication context class references.
+	// Can be blank
+	ACRValues string
+
+	// ClientNotificationToken is the bearer token used to authenticate the
+	// notifications sent to the client in the ping and push modes.
+	ClientNotificationToken string
+
+	// DeliveryMode is the token delivery mode of the client.
+	DeliveryMode string
+
+	// Status is the status of the authentication.
+	Status BackchannelAuthenticationStatus
+
+	// ExpiresIn is the authentication request id expiration in seconds.
+	ExpiresIn int32
+
+	// Interval is the minimum polling interval in seconds.
+	Interval int32
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+
+	// PolledAt is the time of the last token request.
+	PolledAt time.Time
+
+	// AuthTime is the time the end-user authenticated.
+	AuthTime time.Time
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+}
+
+// IsExpiredAt is true if the backchannel authentication expires at time 't'
+func (d *BackchannelAuthentication) IsExpiredAt(t time.Time) bool {
+	return d.ExpireAt().Before(t)
+}
+
+// ExpireAt returns the expiration date.
+func (d *BackchannelAuthentication) ExpireAt() time.Time {
+	return d.CreatedAt.Add(time.Duration(d.ExpiresIn) * time.Second)
+}
+
+// AuthReqIDGen is the authentication request id generator interface.
+type AuthReqIDGen interface {
+	GenerateAuthReqID(data *BackchannelAuthentication) (string, error)
+}
+
+// AuthenticationDevice notifies the authentication devices of end-users of
+// backchannel authentication requests.
+type AuthenticationDevice interface {
+	// NotifyAuthenticationDevice asks the end-user of the backchannel
+	// authentication to authenticate and approve the request on their
+	// device. The end-user decision must be reported with
+	// CompleteBackchannelAuthentication.
+	NotifyAuthenticationDevice(ba *BackchannelAuthentication) error
+}
+
+// BackchannelNotifier sends the notifications of the ping and push modes to
+// the client notification endpoint.
+type BackchannelNotifier interface {
+	// NotifyClient sends the notification to the client notification
+	// endpoint, authenticated with the client notification token.
+	NotifyClient(endpoint, token string, notification map[string]interface{}) error
+}
+
+// BackchannelNotifierDefault is the default backchannel notifier, posting the
+// notifications as JSON.
+type BackchannelNotifierDefault struct {
+	// Client is the HTTP client used to send notifications. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NotifyClient posts the notification to endpoint.
+func (a *BackchannelNotifierDefault) NotifyClient(endpoint, token string, notification map[string]interface{}) error {
+	buf, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	hc := a.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("client notification endpoint returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// backchannelMetadata returns the registered token delivery mode and client
+// notification endpoint of client. The mode defaults to poll.
+func backchannelMetadata(client Client) (string, string) {
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil && md.BackchannelTokenDeliveryMode != "" {
+			return md.BackchannelTokenDeliveryMode, md.BackchannelClientNotificationEndpoint
+		}
+	}
+	return BackchannelModePoll, ""
+}
+
+// getBackchannelStorage returns storage as BackchannelStorage.
+func getBackchannelStorage(storage Storage) (BackchannelStorage, error) {
+	bs, ok := storage.(BackchannelStorage)
+	if !ok {
+		return nil, errors.New("storage does not support backchannel authentication")
+	}
+	return bs, nil
+}
+
+// HandleBackchannelAuthenticationRequest is the http.HandlerFunc for handling
+// backchannel authentication requests.
+//
+// Exactly one of the login_hint, login_hint_token and id_token_hint
+// parameters identifies the end-user. The end-user of an id_token_hint is
+// the subject of the ID token; for the other hints, the Subject must be set
+// before calling FinishBackchannelAuthenticationRequest.
+func (s *Server) HandleBackchannelAuthenticationRequest(w *Response, r *http.Request) *BackchannelAuthentication {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	if !s.Config.isGrantTypeAllowed(CIBAGrant) {
+		w.SetError(ErrUnauthorizedClient)
+		return nil
+	}
+
+	ret := &BackchannelAuthentication{
+		Scope:                   r.Form.Get("scope"),
+		LoginHint:               r.Form.Get("login_hint"),
+		LoginHintToken:          r.Form.Get("login_hint_token"),
+		IDTokenHint:             r.Form.Get("id_token_hint"),
+		BindingMessage:          r.Form.Get("binding_message"),
+		UserCode:                r.Form.Get("user_code"),
+		ACRValues:               r.Form.Get("acr_values"),
+		ClientNotificationToken: r.Form.Get("client_notification_token"),
+		Status:                  BackchannelPending,
+		ExpiresIn:               s.Config.BackchannelExpiration,
+		Interval:                s.Config.BackchannelInterval,
+		CreatedAt:               s.Now(),
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients cannot use backchannel authentication
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use backchannel authentication")
+		return nil
+	}
+
+	// must be an openid request
+	if !hasScope(ret.Scope, ScopeOpenID) {
+		w.SetError(ErrInvalidScope)
+		w.InternalError = errors.New("openid scope required")
+		return nil
+	}
+
+	// exactly one hint is required
+	hints := 0
+	for _, hint := range []string{ret.LoginHint, ret.LoginHintToken, ret.IDTokenHint} {
+		if hint != "" {
+			hints++
+		}
+	}
+	if hints != 1 {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("exactly one of login_hint, login_hint_token and id_token_hint required")
+		return nil
+	}
+
+	// ping and push clients are notified
+	var endpoint string
+	ret.DeliveryMode, endpoint = backchannelMetadata(ret.Client)
+	if ret.DeliveryMode != BackchannelModePoll {
+		if endpoint == "" {
+			w.SetError(ErrUnauthorizedClient)
+			w.InternalError = errors.New("client notification endpoint not registered")
+			return nil
+		}
+		if ret.ClientNotificationToken == "" {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = errors.New("client notification token required")
+			return nil
+		}
+	}
+
+	// authorization details must be of supported types
+	if ret.AuthorizationDetails, err = parseAuthorizationDetails(r.Form.Get("authorization_details")); err == nil {
+		err = s.validateAuthorizationDetails(ret.Client, ret.AuthorizationDetails)
+	}
+	if err != nil {
+		w.SetError(ErrInvalidAuthorizationDetails)
+		w.InternalError = err
+		return nil
+	}
+
+	// requested expiry can only shorten the expiration
+	if v := r.Form.Get("requested_expiry"); v != "" {
+		expiry, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || expiry <= 0 {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = errors.New("invalid requested expiry")
+			return nil
+		}
+		if int32(expiry) < ret.ExpiresIn {
+			ret.ExpiresIn = int32(expiry)
+		}
+	}
+
+	// id token hints must be issued by the server, expired tokens are
+	// accepted
+	if ret.IDTokenHint != "" {
+		claims, err := s.verifyIDTokenHint(ret.IDTokenHint)
+		if err != nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+		ret.Subject = claims.String("sub")
+	}
+
+	return ret
+}
+
+// FinishBackchannelAuthenticationRequest finalizes the request handled by
+// HandleBackchannelAuthenticationRequest, saving the pending backchannel
+// authentication and notifying the authentication device of the end-user.
+func (s *Server) FinishBackchannelAuthenticationRequest(w *Response, r *http.Request, ba *BackchannelAuthentication) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	bs, err := getBackchannelStorage(w.Storage)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+	if s.AuthenticationDevice == nil {
+		w.SetError(ErrServerError)
+		w.InternalError = errors.New("no authentication device")
+		return
+	}
+
+	// end-user must be known
+	if ba.Subject == "" {
+		w.SetError(ErrUnknownUserID)
+		return
+	}
+
+	// generate authentication request id
+	if ba.AuthReqID, err = s.AuthReqIDGen.GenerateAuthReqID(ba); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// save backchannel authentication
+	if err = bs.SaveBackchannelAuthentication(ba); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// notify authentication device
+	if err = s.AuthenticationDevice.NotifyAuthenticationDevice(ba); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		if err := bs.RemoveBackchannelAuthentication(ba.AuthReqID); err != nil {
+			w.InternalError = err
+		}
+		return
+	}
+
+	// output data
+	w.Output["auth_req_id"] = ba.AuthReqID
+	w.Output["expires_in"] = ba.ExpiresIn
+	if ba.DeliveryMode != BackchannelModePush {
+		w.Output["interval"] = ba.Interval
+	}
+}
+
+// backchannelTokenRequest returns the token request of the approved
+// backchannel authentication.
+func (s *Server) backchannelTokenRequest(client Client, ba *BackchannelAuthentication) *TokenRequest {
+	return &TokenRequest{
+		GrantType:       CIBAGrant,
+		Code:            ba.AuthReqID,
+		Client:          client,
+		Scope:           ba.Scope,
+		Subject:         ba.Subject,
+		AuthTime:        ba.AuthTime,
+		UserData:        ba.UserData,
+		RedirectURI:     firstURI(client.GetRedirectURI(), s.Config.RedirectURISeparator),
+		GenerateIDToken: true,
+		GenerateRefresh: true,
+		Expiration:      s.Config.AccessExpiration,
+
+		AuthorizationDetails:      ba.AuthorizationDetails,
+		BackchannelAuthentication: ba,
+	}
+}
+
+// CompleteBackchannelAuthentication records the decision of the end-user on
+// the pending backchannel authentication, reported by the authentication
+// device. Clients in the ping mode are notified; clients in the push mode
+// are sent the tokens, or the error if the authentication was denied.
+func (s *Server) CompleteBackchannelAuthentication(ba *BackchannelAuthentication, authorized bool) error {
+	bs, err := getBackchannelStorage(s.Storage)
+	if err != nil {
+		return err
+	}
+	if ba.Status != BackchannelPending {
+		return errors.New("backchannel authentication is not pending")
+	}
+	if ba.IsExpiredAt(s.Now()) {
+		return errors.New("backchannel authentication expired")
+	}
+
+	ba.Status = BackchannelDenied
+	if authorized {
+		ba.Status = BackchannelApproved
+		if ba.AuthTime.IsZero() {
+			ba.AuthTime = s.Now()
+		}
+	}
+	if err = bs.SaveBackchannelAuthentication(ba); err != nil {
+		return err
+	}
+
+	_, endpoint := backchannelMetadata(ba.Client)
+	switch ba.DeliveryMode {
+	case BackchannelModePing:
+		return s.BackchannelNotifier.NotifyClient(endpoint, ba.ClientNotificationToken, map[string]interface{}{
+			"auth_req_id": ba.AuthReqID,
+		})
+
+	case BackchannelModePush:
+		// generate tokens, see:
+		// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.10.3.1
+		w := s.NewResponse()
+		if authorized {
+			ar := s.backchannelTokenRequest(ba.Client, ba)
+			ar.Authorized = true
+			s.FinishTokenRequest(w, &http.Request{Form: url.Values{}}, ar)
+		} else {
+			w.SetError(ErrAccessDenied)
+			if err = bs.RemoveBackchannelAuthentication(ba.AuthReqID); err != nil {
+				return err
+			}
+		}
+		if w.IsError && w.InternalError != nil {
+			return w.InternalError
+		}
+
+		notification := map[string]interface{}{"auth_req_id": ba.AuthReqID}
+		for k, v := range w.Output {
+			notification[k] = v
+		}
+		return s.BackchannelNotifier.NotifyClient(endpoint, ba.ClientNotificationToken, notification)
+	}
+	return nil
+}
+
+func (s *Server) handleBackchannelRequest(w *Response, r *http.Request) *TokenRequest {
+	bs, err := getBackchannelStorage(w.Storage)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil
+	}
+
+	// "auth_req_id" is required
+	authReqID := r.Form.Get("auth_req_id")
+	if authReqID == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must have a valid client
+	client := s.authenticateClient(w, r)
+	if client == nil {
+		return nil
+	}
+
+	// public clients cannot use the ciba grant
+	if isPublicClient(client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use the ciba grant")
+		return nil
+	}
+
+	// must be a valid authentication request id
+	ba, err := bs.LoadBackchannelAuthentication(authReqID)
+	if err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+	if ba == nil || ba.Client == nil {
+		w.SetError(ErrInvalidGrant)
+		return nil
+	}
+
+	// authentication request id must be from the client
+	if ba.Client.GetID() != client.GetID() {
+		w.SetError(ErrInvalidGrant)
+		return nil
+	}
+
+	// tokens of push clients are only sent to the notification endpoint
+	if ba.DeliveryMode == BackchannelModePush {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("push mode clients cannot use the token endpoint")
+		return nil
+	}
+
+	now := s.Now()
+	if ba.IsExpiredAt(now) {
+		w.SetError(ErrExpiredToken)
+		return nil
+	}
+
+	switch ba.Status {
+	case BackchannelDenied:
+		if err = bs.RemoveBackchannelAuthentication(ba.AuthReqID); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return nil
+		}
+		w.SetError(ErrAccessDenied)
+		return nil
+
+	case BackchannelPending:
+		e := ErrAuthorizationPending
+
+		// polling too quickly, increase interval, see:
+		// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.11
+		if !ba.PolledAt.IsZero() && now.Sub(ba.PolledAt) < time.Duration(ba.Interval)*time.Second {
+			ba.Interval += 5
+			e = ErrSlowDown
+		}
+		ba.PolledAt = now
+
+		if err = bs.SaveBackchannelAuthentication(ba); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return nil
+		}
+		w.SetError(e)
+		return nil
+	}
+
+	return s.backchannelTokenRequest(client, ba)
+}
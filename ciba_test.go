@@ -0,0 +1,432 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testAuthenticationDevice []*BackchannelAuthentication
+
+func (d *testAuthenticationDevice) NotifyAuthenticationDevice(ba *BackchannelAuthentication) error {
+	*d = append(*d, ba)
+	return nil
+}
+
+type testBackchannelNotification struct {
+	endpoint     string
+	token        string
+	notification map[string]interface{}
+}
+
+type testBackchannelNotifier []testBackchannelNotification
+
+func (n *testBackchannelNotifier) NotifyClient(endpoint, token string, notification map[string]interface{}) error {
+	*n = append(*n, testBackchannelNotification{endpoint, token, notification})
+	return nil
+}
+
+// testBackchannelClients are the ping and push mode clients.
+var testBackchannelClients = []*DefaultClient{
+	{
+		ID:          BackchannelModePing,
+		Secret:      "aabbccdd",
+		RedirectURI: "http://localhost:14000/appauth",
+		Metadata: ClientMetadata{
+			BackchannelTokenDeliveryMode:          BackchannelModePing,
+			BackchannelClientNotificationEndpoint: "https://localhost:14000/notify",
+		},
+	},
+	{
+		ID:          BackchannelModePush,
+		Secret:      "aabbccdd",
+		RedirectURI: "http://localhost:14000/appauth",
+		Metadata: ClientMetadata{
+			BackchannelTokenDeliveryMode:          BackchannelModePush,
+			BackchannelClientNotificationEndpoint: "https://localhost:14000/notify",
+		},
+	},
+}
+
+func TestBackchannelPoll(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	device := &testAuthenticationDevice{}
+	server.AuthenticationDevice = device
+	server.BackchannelNotifier = &testBackchannelNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+	now := time.Now()
+	server.Now = func() time.Time { return now }
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{
+		"scope":           {"openid"},
+		"login_hint":      {"jane"},
+		"binding_message": {"W4SCT"},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		ba.Subject = "user"
+		server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	authReqID, _ := resp.Output["auth_req_id"].(string)
+	tokenForm := url.Values{
+		"grant_type":  {string(CIBAGrant)},
+		"auth_req_id": {authReqID},
+	}
+	if authReqID == "" || resp.Output["interval"] != int32(5) {
+		t.Fatalf("Unexpected output: %v", resp.Output)
+	}
+	if len(*device) != 1 || (*device)[0].BindingMessage != "W4SCT" || (*device)[0].Subject != "user" {
+		t.Fatalf("Authentication device should have been notified")
+	}
+
+	// pending
+	if resp = doTestTokenRequest(t, server, "1234", tokenForm); resp.ErrorType != ErrAuthorizationPending.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrAuthorizationPending.Type, resp.ErrorType)
+	}
+	if resp = doTestTokenRequest(t, server, "1234", tokenForm); resp.ErrorType != ErrSlowDown.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrSlowDown.Type, resp.ErrorType)
+	}
+
+	// other client
+	if resp = doTestTokenRequest(t, server, BackchannelModePing, tokenForm); resp.ErrorType != ErrInvalidGrant.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrInvalidGrant.Type, resp.ErrorType)
+	}
+
+	// approve
+	if err := server.CompleteBackchannelAuthentication((*device)[0], true); err != nil {
+		t.Fatal(err)
+	}
+	resp = doTestTokenRequest(t, server, "1234", tokenForm)
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	if resp.Output["access_token"] == nil || resp.Output["id_token"] == nil {
+		t.Fatalf("Unexpected output: %v", resp.Output)
+	}
+
+	// can only be used once
+	if _, ok := storage.BackchannelAuthentications[authReqID]; ok {
+		t.Fatalf("Backchannel authentication should have been removed")
+	}
+}
+
+func TestBackchannelPing(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	device := &testAuthenticationDevice{}
+	server.AuthenticationDevice = device
+	notifier := &testBackchannelNotifier{}
+	server.BackchannelNotifier = notifier
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(BackchannelModePing, "aabbccdd")
+	req.Form = url.Values{
+		"scope":                     {"openid"},
+		"login_hint":                {"jane"},
+		"client_notification_token": {"notify"},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		ba.Subject = "user"
+		server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	authReqID := resp.Output["auth_req_id"].(string)
+	tokenForm := url.Values{
+		"grant_type":  {string(CIBAGrant)},
+		"auth_req_id": {authReqID},
+	}
+
+	if err := server.CompleteBackchannelAuthentication((*device)[0], true); err != nil {
+		t.Fatal(err)
+	}
+	if len(*notifier) != 1 {
+		t.Fatalf("Client should have been notified")
+	}
+	n := (*notifier)[0]
+	if n.endpoint != "https://localhost:14000/notify" || n.token != "notify" || n.notification["auth_req_id"] != authReqID || len(n.notification) != 1 {
+		t.Fatalf("Unexpected notification: %v", n)
+	}
+
+	if resp = doTestTokenRequest(t, server, BackchannelModePing, tokenForm); resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+}
+
+func TestBackchannelPush(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	device := &testAuthenticationDevice{}
+	server.AuthenticationDevice = device
+	notifier := &testBackchannelNotifier{}
+	server.BackchannelNotifier = notifier
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(BackchannelModePush, "aabbccdd")
+	req.Form = url.Values{
+		"scope":                     {"openid"},
+		"login_hint":                {"jane"},
+		"client_notification_token": {"notify"},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		ba.Subject = "user"
+		server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	authReqID := resp.Output["auth_req_id"].(string)
+	tokenForm := url.Values{
+		"grant_type":  {string(CIBAGrant)},
+		"auth_req_id": {authReqID},
+	}
+	if _, ok := resp.Output["interval"]; ok {
+		t.Fatalf("Push mode should not have an interval")
+	}
+
+	// token endpoint can't be used
+	if resp = doTestTokenRequest(t, server, BackchannelModePush, tokenForm); resp.ErrorType != ErrUnauthorizedClient.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrUnauthorizedClient.Type, resp.ErrorType)
+	}
+
+	if err := server.CompleteBackchannelAuthentication((*device)[0], true); err != nil {
+		t.Fatal(err)
+	}
+	if len(*notifier) != 1 {
+		t.Fatalf("Client should have been notified")
+	}
+	n := (*notifier)[0].notification
+	if n["auth_req_id"] != authReqID || n["access_token"] == nil || n["refresh_token"] == nil {
+		t.Fatalf("Unexpected notification: %v", n)
+	}
+
+	jt, err := parseJWT(n["id_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := jt.Claims; c.String("urn:openid:params:jwt:claim:auth_req_id") != authReqID || c.String("urn:openid:params:jwt:claim:rt_hash") == "" || c.String("at_hash") == "" {
+		t.Fatalf("Unexpected id token claims: %v", c)
+	}
+}
+
+func TestBackchannelDenied(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	device := &testAuthenticationDevice{}
+	server.AuthenticationDevice = device
+	server.BackchannelNotifier = &testBackchannelNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{
+		"scope":      {"openid"},
+		"login_hint": {"jane"},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		ba.Subject = "user"
+		server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	authReqID := resp.Output["auth_req_id"].(string)
+	tokenForm := url.Values{
+		"grant_type":  {string(CIBAGrant)},
+		"auth_req_id": {authReqID},
+	}
+
+	if err := server.CompleteBackchannelAuthentication((*device)[0], false); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.CompleteBackchannelAuthentication((*device)[0], true); err == nil {
+		t.Fatalf("Completed backchannel authentication should not be completed again")
+	}
+	if resp = doTestTokenRequest(t, server, "1234", tokenForm); resp.ErrorType != ErrAccessDenied.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrAccessDenied.Type, resp.ErrorType)
+	}
+}
+
+func TestBackchannelIDTokenHint(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	device := &testAuthenticationDevice{}
+	server.AuthenticationDevice = device
+	server.BackchannelNotifier = &testBackchannelNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+
+	client := storage.Clients["1234"]
+	idToken, err := server.generateIDToken(&IDToken{Client: client, Subject: "hinted", ExpiresIn: 3600, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{
+		"scope":         {"openid"},
+		"id_token_hint": {idToken},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	if (*device)[0].Subject != "hinted" {
+		t.Fatalf("Unexpected subject: %s", (*device)[0].Subject)
+	}
+}
+
+func TestBackchannelInvalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, CIBAGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	server.AuthenticationDevice = &testAuthenticationDevice{}
+	server.BackchannelNotifier = &testBackchannelNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testBackchannelClients {
+		storage.Clients[client.ID] = client
+	}
+
+	var tests = []struct {
+		client string
+		form   url.Values
+		err    string
+	}{
+		{"1234", url.Values{"scope": {"profile"}, "login_hint": {"jane"}}, ErrInvalidScope.Type},
+		{"1234", url.Values{"scope": {"openid"}}, ErrInvalidRequest.Type},
+		{"1234", url.Values{"scope": {"openid"}, "login_hint": {"jane"}, "login_hint_token": {"x"}}, ErrInvalidRequest.Type},
+		{"1234", url.Values{"scope": {"openid"}, "id_token_hint": {"x.y.z"}}, ErrInvalidRequest.Type},
+		{"1234", url.Values{"scope": {"openid"}, "login_hint": {"jane"}, "requested_expiry": {"-1"}}, ErrInvalidRequest.Type},
+		{"1234", url.Values{"scope": {"openid"}, "login_hint": {"john"}}, ErrUnknownUserID.Type},
+		{BackchannelModePing, url.Values{"scope": {"openid"}, "login_hint": {"jane"}}, ErrInvalidRequest.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth(tt.client, "aabbccdd")
+		req.Form = tt.form
+		req.PostForm = url.Values{}
+		if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+			// only the end-user "jane" is known
+			if ba.LoginHint == "jane" {
+				ba.Subject = "user"
+			}
+			server.FinishBackchannelAuthenticationRequest(resp, req, ba)
+		}
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestBackchannelPublicClient(t *testing.T) {
+	server := newTestServer(t, CIBAGrant)
+	server.AuthenticationDevice = &testAuthenticationDevice{}
+	client := &DefaultClient{
+		ID:          "5678",
+		Type:        ClientTypePublic,
+		RedirectURI: "http://localhost:14000/otherauth",
+	}
+	if err := server.Storage.(*MemStorage).SetClient(client.ID, client); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/bc-authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"client_id":  {client.ID},
+		"scope":      {"openid"},
+		"login_hint": {"jane"},
+	}
+	req.PostForm = url.Values{}
+	if ba := server.HandleBackchannelAuthenticationRequest(resp, req); ba != nil {
+		t.Fatalf("Public client should not be able to use backchannel authentication")
+	}
+	if resp.ErrorType != ErrUnauthorizedClient.Type {
+		t.Errorf("Expected error %q, got: %q", ErrUnauthorizedClient.Type, resp.ErrorType)
+	}
+}
@@ -1,5 +1,57 @@
 package oauthlib
 
+import (
+	"crypto/subtle"
+	"crypto/x509"
+)
+
+// Client authentication methods, see:
+// http://tools.ietf.org/html/rfc7591#section-2
+const (
+	// ClientAuthSecretBasic is the client_secret_basic authentication method.
+	ClientAuthSecretBasic = "client_secret_basic"
+
+	// ClientAuthSecretPost is the client_secret_post authentication method.
+	ClientAuthSecretPost = "client_secret_post"
+
+	// ClientAuthSecretJWT is the client_secret_jwt authentication method.
+	ClientAuthSecretJWT = "client_secret_jwt"
+
+	// ClientAuthPrivateKeyJWT is the private_key_jwt authentication method.
+	ClientAuthPrivateKeyJWT = "private_key_jwt"
+
+	// ClientAuthNone is the authentication method of public clients.
+	ClientAuthNone = "none"
+
+	// ClientAuthTLS is the PKI mutual TLS authentication method, see:
+	// http://tools.ietf.org/html/rfc8705#section-2.1
+	ClientAuthTLS = "tls_client_auth"
+
+	// ClientAuthSelfSignedTLS is the self-signed certificate mutual TLS
+	// authentication method, see:
+	// http://tools.ietf.org/html/rfc8705#section-2.2
+	ClientAuthSelfSignedTLS = "self_signed_tls_client_auth"
+)
+
+// ClientType is the client type, see:
+// http://tools.ietf.org/html/rfc6749#section-2.1
+type ClientType string
+
+const (
+	// ClientTypeConfidential is the type of clients capable of maintaining
+	// the confidentiality of their credentials.
+	ClientTypeConfidential ClientType = "confidential"
+
+	// ClientTypePublic is the type of clients incapable of maintaining the
+	// confidentiality of their credentials, such as native and browser
+	// applications.
+	ClientTypePublic ClientType = "public"
+)
+
+// ClientAssertionTypeJWTBearer is the JWT client assertion type, see:
+// http://tools.ietf.org/html/rfc7523#section-2.2
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
 // Client information.
 type Client interface {
 	// Client id
@@ -23,6 +75,156 @@ type ClientSecretMatcher interface {
 	ClientSecretMatches(secret string) bool
 }
 
+// ClientSecretKeyGetter is an optional interface clients can implement which
+// provides the key verifying HMAC signatures made with the client secret, of
+// client_secret_jwt assertions and request objects. As GetSecret is never
+// called for clients implementing ClientSecretMatcher, these clients must
+// implement ClientSecretKeyGetter to use HMAC signatures.
+type ClientSecretKeyGetter interface {
+	// GetSecretKey returns the HMAC key. Nil if the client does not use
+	// HMAC signatures
+	GetSecretKey() []byte
+}
+
+// ClientTypeGetter is an optional interface clients can implement which
+// declares the client type. Clients not implementing ClientTypeGetter are
+// public if their secret is blank.
+type ClientTypeGetter interface {
+	// GetClientType returns the client type
+	GetClientType() ClientType
+}
+
+// ClientAuthMethodGetter is an optional interface clients can implement which
+// declares the client authentication method the client registered. If a
+// Client implements ClientAuthMethodGetter and returns a non-blank method,
+// the framework rejects authentication using any other method.
+type ClientAuthMethodGetter interface {
+	// GetTokenEndpointAuthMethod returns the client authentication method
+	GetTokenEndpointAuthMethod() string
+}
+
+// ClientRegistrationTokenMatcher is an optional interface clients can
+// implement to allow their configuration to be managed using a registration
+// access token.
+type ClientRegistrationTokenMatcher interface {
+	// RegistrationAccessTokenMatches returns true if the given token matches
+	RegistrationAccessTokenMatches(token string) bool
+}
+
+// ClientCertificateMatcher is an optional interface clients can implement to
+// authenticate using a mutual TLS client certificate.
+type ClientCertificateMatcher interface {
+	// ClientCertificateMatches returns true if the certificate authenticates
+	// the client using the mutual TLS method (ClientAuthTLS or
+	// ClientAuthSelfSignedTLS)
+	ClientCertificateMatches(method string, cert *x509.Certificate) bool
+}
+
+// ClientMetadata is the registered metadata of a client, see:
+// http://tools.ietf.org/html/rfc7591#section-2
+type ClientMetadata struct {
+	// RedirectURIs are the redirection URIs of the client.
+	RedirectURIs []string `json:"redirect_uris,omitempty"`
+
+	// GrantTypes are the grant types the client may use.
+	GrantTypes []string `json:"grant_types,omitempty"`
+
+	// ResponseTypes are the response types the client may use.
+	ResponseTypes []string `json:"response_types,omitempty"`
+
+	// TokenEndpointAuthMethod is the client authentication method.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty"`
+
+	// Scope is the space separated list of scopes the client may request.
+	Scope string `json:"scope,omitempty"`
+
+	// ClientName is the human readable name of the client.
+	ClientName string `json:"client_name,omitempty"`
+
+	// JWKS are the client's public keys, used to verify private_key_jwt
+	// client assertions and self_signed_tls_client_auth certificates.
+	JWKS *JSONWebKeySet `json:"jwks,omitempty"`
+
+	// TLSClientAuthSubjectDN is the expected subject distinguished name of
+	// the tls_client_auth certificate.
+	TLSClientAuthSubjectDN string `json:"tls_client_auth_subject_dn,omitempty"`
+
+	// TLSClientAuthSANDNS is the expected dNSName SAN of the tls_client_auth
+	// certificate.
+	TLSClientAuthSANDNS string `json:"tls_client_auth_san_dns,omitempty"`
+
+	// TLSClientAuthSANURI is the expected uniformResourceIdentifier SAN of
+	// the tls_client_auth certificate.
+	TLSClientAuthSANURI string `json:"tls_client_auth_san_uri,omitempty"`
+
+	// TLSClientAuthSANIP is the expected iPAddress SAN of the
+	// tls_client_auth certificate.
+	TLSClientAuthSANIP string `json:"tls_client_auth_san_ip,omitempty"`
+
+	// TLSClientAuthSANEmail is the expected rfc822Name SAN of the
+	// tls_client_auth certificate.
+	TLSClientAuthSANEmail string `json:"tls_client_auth_san_email,omitempty"`
+
+	// TLSClientCertificateBoundAccessTokens toggles if access tokens issued
+	// to the client are bound to its mutual TLS certificate.
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+
+	// DPoPBoundAccessTokens toggles if the client must send a DPoP proof
+	// when requesting tokens.
+	DPoPBoundAccessTokens bool `json:"dpop_bound_access_tokens,omitempty"`
+
+	// RequirePushedAuthorizationRequests toggles if the client must push its
+	// authorization requests.
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty"`
+
+	// RequestURIs are the request uris the client may pass request objects
+	// by reference from.
+	RequestURIs []string `json:"request_uris,omitempty"`
+
+	// RequireSignedRequestObject toggles if the client must send its
+	// authorization requests as signed request objects.
+	RequireSignedRequestObject bool `json:"require_signed_request_object,omitempty"`
+
+	// UserInfoSignedResponseAlg is the signing algorithm of UserInfo
+	// responses. If blank, responses are not signed.
+	UserInfoSignedResponseAlg string `json:"userinfo_signed_response_alg,omitempty"`
+
+	// BackchannelTokenDeliveryMode is the backchannel authentication token
+	// delivery mode ("poll", "ping" or "push").
+	BackchannelTokenDeliveryMode string `json:"backchannel_token_delivery_mode,omitempty"`
+
+	// BackchannelClientNotificationEndpoint is the endpoint notified of
+	// completed backchannel authentications in the ping and push modes.
+	BackchannelClientNotificationEndpoint string `json:"backchannel_client_notification_endpoint,omitempty"`
+
+	// PostLogoutRedirectURIs are the URIs the end-user may be redirected to
+	// after logout.
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris,omitempty"`
+
+	// FrontchannelLogoutURI is the URI rendered in an iframe by the
+	// end-session endpoint to log the end-user out of the client.
+	FrontchannelLogoutURI string `json:"frontchannel_logout_uri,omitempty"`
+
+	// FrontchannelLogoutSessionRequired toggles if the "iss" and "sid"
+	// parameters are added to the FrontchannelLogoutURI.
+	FrontchannelLogoutSessionRequired bool `json:"frontchannel_logout_session_required,omitempty"`
+
+	// BackchannelLogoutURI is the URI logout tokens are posted to when the
+	// end-user logs out.
+	BackchannelLogoutURI string `json:"backchannel_logout_uri,omitempty"`
+
+	// BackchannelLogoutSessionRequired toggles if the client requires the
+	// "sid" claim in logout tokens.
+	BackchannelLogoutSessionRequired bool `json:"backchannel_logout_session_required,omitempty"`
+}
+
+// ClientMetadataGetter is an optional interface clients can implement which
+// provides the registered client metadata.
+type ClientMetadataGetter interface {
+	// GetMetadata returns the client metadata.
+	GetMetadata() *ClientMetadata
+}
+
 // DefaultClient stores all data in struct variables
 type DefaultClient struct {
 	// ID is the client id.
@@ -34,8 +236,25 @@ type DefaultClient struct {
 	// RedirectURI is the redirect uri for the client.
 	RedirectURI string
 
+	// Type is the client type. If blank, the client is public if its token
+	// endpoint auth method is "none", or if it has neither a token endpoint
+	// auth method nor a secret, and confidential otherwise.
+	Type ClientType
+
 	// UserData is the user data.
 	UserData interface{}
+
+	// Metadata is the registered client metadata.
+	Metadata ClientMetadata
+
+	// RegistrationAccessToken is the token used to manage the client
+	// configuration. If blank, the client configuration can't be managed.
+	RegistrationAccessToken string
+
+	// CertificateThumbprint is the SHA-256 thumbprint (see
+	// CertificateThumbprint) of the client's self-signed mutual TLS
+	// certificate. Used in addition to the keys in Metadata.JWKS.
+	CertificateThumbprint string
 }
 
 // GetID retrieves the client id.
@@ -63,3 +282,69 @@ func (d *DefaultClient) GetUserData() interface{} {
 func (d *DefaultClient) ClientSecretMatches(secret string) bool {
 	return d.Secret == secret
 }
+
+// GetSecretKey provides compatibility with the ClientSecretKeyGetter
+// interface.
+func (d *DefaultClient) GetSecretKey() []byte {
+	if d.Secret == "" {
+		return nil
+	}
+	return []byte(d.Secret)
+}
+
+// GetClientType provides compatibility with the ClientTypeGetter interface.
+func (d *DefaultClient) GetClientType() ClientType {
+	switch {
+	case d.Type != "":
+		return d.Type
+	case d.Metadata.TokenEndpointAuthMethod == ClientAuthNone:
+		return ClientTypePublic
+	case d.Metadata.TokenEndpointAuthMethod == "" && d.Secret == "":
+		return ClientTypePublic
+	}
+	return ClientTypeConfidential
+}
+
+// GetMetadata provides compatibility with the ClientMetadataGetter interface.
+func (d *DefaultClient) GetMetadata() *ClientMetadata {
+	return &d.Metadata
+}
+
+// GetTokenEndpointAuthMethod provides compatibility with the
+// ClientAuthMethodGetter interface.
+func (d *DefaultClient) GetTokenEndpointAuthMethod() string {
+	return d.Metadata.TokenEndpointAuthMethod
+}
+
+// RegistrationAccessTokenMatches provides compatibility with the
+// ClientRegistrationTokenMatcher interface.
+func (d *DefaultClient) RegistrationAccessTokenMatches(token string) bool {
+	return d.RegistrationAccessToken != "" &&
+		subtle.ConstantTimeCompare([]byte(d.RegistrationAccessToken), []byte(token)) == 1
+}
+
+// ClientCertificateMatches provides compatibility with the
+// ClientCertificateMatcher interface.
+func (d *DefaultClient) ClientCertificateMatches(method string, cert *x509.Certificate) bool {
+	switch method {
+	case ClientAuthTLS:
+		return certificateSubjectMatches(&d.Metadata, cert)
+	case ClientAuthSelfSignedTLS:
+		if d.CertificateThumbprint != "" && d.CertificateThumbprint == CertificateThumbprint(cert) {
+			return true
+		}
+		return certificateKeyMatches(d.Metadata.JWKS, cert)
+	}
+	return false
+}
+
+// isPublicClient determines if the client is a public client.
+func isPublicClient(client Client) bool {
+	if c, ok := client.(ClientTypeGetter); ok {
+		return c.GetClientType() == ClientTypePublic
+	}
+	if c, ok := client.(ClientSecretMatcher); ok {
+		return c.ClientSecretMatches("")
+	}
+	return client.GetSecret() == ""
+}
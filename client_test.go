@@ -16,3 +16,25 @@ func TestClientIntfUserData(t *testing.T) {
 		t.Error("Returned interface is not a reference")
 	}
 }
+
+func TestClientType(t *testing.T) {
+	var tests = []struct {
+		client Client
+		public bool
+	}{
+		{&DefaultClient{Secret: "aabbccdd"}, false},
+		{&DefaultClient{}, true},
+		{&DefaultClient{Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthPrivateKeyJWT}}, false},
+		{&DefaultClient{Type: ClientTypePublic, Secret: "aabbccdd"}, true},
+		{&DefaultClient{Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthNone}}, true},
+		{&DefaultClient{Type: ClientTypeConfidential, Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthNone}}, false},
+		{&clientWithoutMatcher{}, true},
+		{&clientWithoutMatcher{Secret: "aabbccdd"}, false},
+	}
+
+	for i, tt := range tests {
+		if public := isPublicClient(tt.client); public != tt.public {
+			t.Errorf("Expected public to be %t (%d), got: %t", tt.public, i, public)
+		}
+	}
+}
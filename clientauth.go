@@ -0,0 +1,198 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// authenticateClient authenticates the client sending the request, using the
+// HTTP Basic authorization header, the client credentials in the request body,
+// a JWT client assertion, or a mutual TLS client certificate. Public clients
+// and mutual TLS clients identify themselves with the "client_id" parameter,
+// so endpoints and grants not open to public clients must reject them. Sets
+// an error on the response if authentication fails or a server error occurs.
+//
+// The request form must have been parsed.
+func (s *Server) authenticateClient(w *Response, r *http.Request) Client {
+	basic := r.Header.Get("Authorization") != ""
+	post := r.PostForm.Get("client_secret") != ""
+	assertion := r.Form.Get("client_assertion_type") != "" || r.Form.Get("client_assertion") != ""
+
+	// only one authentication method may be used, see:
+	// http://tools.ietf.org/html/rfc6749#section-2.3
+	if (basic && post) || (basic && assertion) || (post && assertion) {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("multiple client authentication methods sent")
+		return nil
+	}
+
+	var client Client
+	var method string
+	switch {
+	case assertion:
+		client, method = s.authenticateClientAssertion(w, r)
+
+	case post:
+		auth := &BasicAuth{
+			Username: r.PostForm.Get("client_id"),
+			Password: r.PostForm.Get("client_secret"),
+		}
+		client, method = getClient(auth, w.Storage, w), ClientAuthSecretPost
+
+	case !basic && r.Form.Get("client_id") != "":
+		client, method = s.authenticateClientID(w, r)
+
+	default:
+		auth := s.getClientAuth(w, r)
+		if auth == nil {
+			return nil
+		}
+		client, method = getClient(auth, w.Storage, w), ClientAuthSecretBasic
+	}
+	if client == nil {
+		return nil
+	}
+
+	// client must use its registered authentication method
+	if c, ok := client.(ClientAuthMethodGetter); ok {
+		if m := c.GetTokenEndpointAuthMethod(); m != "" && m != method {
+			w.SetError(ErrInvalidClient)
+			w.InternalError = errors.New("client authentication method not allowed: " + method)
+			return nil
+		}
+	}
+
+	return client
+}
+
+// authenticateClientID authenticates the client identified by the
+// "client_id" parameter, returning the client and the authentication method
+// used. The client must be a public client or use mutual TLS authentication.
+// Sets an error on the response if authentication fails or a server error
+// occurs.
+func (s *Server) authenticateClientID(w *Response, r *http.Request) (Client, string) {
+	client, err := w.Storage.GetClient(r.Form.Get("client_id"))
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil, ""
+	}
+	if client == nil {
+		w.SetError(ErrInvalidClient)
+		return nil, ""
+	}
+
+	var method string
+	if c, ok := client.(ClientAuthMethodGetter); ok {
+		method = c.GetTokenEndpointAuthMethod()
+	}
+	switch {
+	case method == ClientAuthTLS || method == ClientAuthSelfSignedTLS:
+		if !authenticateClientCertificate(w, r, client, method) {
+			return nil, ""
+		}
+	case isPublicClient(client):
+		method = ClientAuthNone
+	default:
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client authentication not sent")
+		return nil, ""
+	}
+
+	if client.GetRedirectURI() == "" {
+		w.SetError(ErrUnauthorizedClient)
+		return nil, ""
+	}
+	return client, method
+}
+
+// clientAssertionKeys returns the keys used to verify a client assertion
+// signed with alg.
+func clientAssertionKeys(client Client, alg string) *JSONWebKeySet {
+	// client_secret_jwt uses the client secret as the HMAC key, which can't
+	// be retrieved from clients matching their secret
+	if strings.HasPrefix(alg, "HS") {
+		var key []byte
+		if c, ok := client.(ClientSecretKeyGetter); ok {
+			key = c.GetSecretKey()
+		} else if _, ok := client.(ClientSecretMatcher); !ok && client.GetSecret() != "" {
+			key = []byte(client.GetSecret())
+		}
+		if len(key) == 0 {
+			return nil
+		}
+		return &JSONWebKeySet{Keys: []JSONWebKey{{Key: key}}}
+	}
+
+	// private_key_jwt uses the client's registered keys
+	if c, ok := client.(ClientMetadataGetter); ok {
+		return c.GetMetadata().JWKS
+	}
+	return nil
+}
+
+// authenticateClientAssertion authenticates the client using a JWT client
+// assertion, returning the client and the authentication method used, see:
+// http://tools.ietf.org/html/rfc7523#section-2.2
+func (s *Server) authenticateClientAssertion(w *Response, r *http.Request) (Client, string) {
+	if r.Form.Get("client_assertion_type") != ClientAssertionTypeJWTBearer {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("unsupported client assertion type")
+		return nil, ""
+	}
+
+	t, err := parseJWT(r.Form.Get("client_assertion"))
+	if err != nil {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = err
+		return nil, ""
+	}
+
+	// issuer and subject must be the client id
+	id := t.Claims.String("sub")
+	if id == "" || t.Claims.String("iss") != id {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client assertion iss and sub must be the client id")
+		return nil, ""
+	}
+	if cid := r.Form.Get("client_id"); cid != "" && cid != id {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client assertion sub does not match client id")
+		return nil, ""
+	}
+
+	client, err := w.Storage.GetClient(id)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil, ""
+	}
+	if client == nil {
+		w.SetError(ErrInvalidClient)
+		return nil, ""
+	}
+
+	// verify signature
+	if err = t.verifyWithKeySet(clientAssertionKeys(client, t.alg())); err != nil {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = err
+		return nil, ""
+	}
+
+	// must have valid claims
+	if err = s.validateAssertion(w.Storage, t.Claims); err != nil {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = err
+		return nil, ""
+	}
+
+	if client.GetRedirectURI() == "" {
+		w.SetError(ErrUnauthorizedClient)
+		return nil, ""
+	}
+	if strings.HasPrefix(t.alg(), "HS") {
+		return client, ClientAuthSecretJWT
+	}
+	return client, ClientAuthPrivateKeyJWT
+}
@@ -0,0 +1,213 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testSecretMatcherClient is a client matching its secret, without a
+// secret key for HMAC signatures.
+type testSecretMatcherClient struct {
+	Client
+}
+
+func (c testSecretMatcherClient) ClientSecretMatches(secret string) bool {
+	return secret == c.GetSecret()
+}
+
+func TestAuthenticateClientAssertion(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.Endpoints.Token = "http://localhost:14000/token"
+	storage := NewTestStorage(t)
+	err = storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			TokenEndpointAuthMethod: ClientAuthPrivateKeyJWT,
+			JWKS:                    &JSONWebKeySet{Keys: []JSONWebKey{{Key: &key.PublicKey}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = storage.SetClient("9012", testSecretMatcherClient{&DefaultClient{
+		ID:          "9012",
+		Secret:      "aabbccdd",
+		RedirectURI: "http://localhost:14000/otherauth",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	claims := func(id, jti string) JWTClaims {
+		return JWTClaims{
+			"iss": id,
+			"sub": id,
+			"aud": "http://localhost:14000/token",
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"jti": jti,
+		}
+	}
+	sign := func(key interface{}, alg string, c JWTClaims) string {
+		assertion, err := signJWT(key, alg, nil, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return assertion
+	}
+
+	var tests = []struct {
+		assertion string
+		basic     bool
+		id        string
+	}{
+		{sign(key, AlgES256, claims("5678", "j1")), false, "5678"},
+		{sign(key, AlgES256, claims("5678", "j1")), false, ""}, // replayed
+		{sign(otherKey, AlgES256, claims("5678", "j2")), false, ""},
+		{sign([]byte("aabbccdd"), AlgHS256, claims("1234", "j3")), false, "1234"},
+		{sign([]byte("invalid"), AlgHS256, claims("1234", "j4")), false, ""},
+		{sign(key, AlgES256, claims("5678", "j5")), true, ""},
+		{sign([]byte("aabbccdd"), AlgHS256, claims("9012", "j6")), false, ""},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.basic {
+			req.SetBasicAuth("1234", "aabbccdd")
+		}
+		req.Form = url.Values{}
+		req.Form.Set("client_assertion_type", ClientAssertionTypeJWTBearer)
+		req.Form.Set("client_assertion", tt.assertion)
+
+		client := server.authenticateClient(resp, req)
+		if tt.id == "" {
+			if client != nil || !resp.IsError {
+				t.Errorf("Expected authentication to fail (%d)", i)
+			}
+			continue
+		}
+
+		if client == nil {
+			t.Errorf("Expected authentication to succeed (%d), got: %v", i, resp.InternalError)
+			continue
+		}
+
+		if client.GetID() != tt.id {
+			t.Errorf("Unexpected client (%d): %s", i, client.GetID())
+		}
+	}
+}
+
+func TestAuthenticateClientSecretPost(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	err := storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		Secret:      "eeffgghh",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			TokenEndpointAuthMethod: ClientAuthSecretBasic,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		id, secret string
+		basic      bool
+		valid      bool
+	}{
+		{"1234", "aabbccdd", false, true},
+		{"1234", "invalid", false, false},
+		{"1234", "aabbccdd", true, false},  // multiple methods
+		{"5678", "eeffgghh", false, false}, // registered client_secret_basic
+		{"5678", "eeffgghh", true, true},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		if tt.basic {
+			req.SetBasicAuth(tt.id, tt.secret)
+		}
+		if !tt.basic || !tt.valid {
+			req.PostForm.Set("client_id", tt.id)
+			req.PostForm.Set("client_secret", tt.secret)
+		}
+
+		client := server.authenticateClient(resp, req)
+		if tt.valid && (client == nil || client.GetID() != tt.id) {
+			t.Errorf("Expected authentication to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && client != nil {
+			t.Errorf("Expected authentication to fail (%d)", i)
+		}
+	}
+}
+
+func TestAuthenticatePublicClient(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	err := storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		Type:        ClientTypePublic,
+		RedirectURI: "http://localhost:14000/otherauth",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		id    string
+		valid bool
+	}{
+		{"5678", true},
+		{"1234", false}, // confidential
+		{"unknown", false},
+		{"", false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("client_id", tt.id)
+
+		client := server.authenticateClient(resp, req)
+		if tt.valid && (client == nil || client.GetID() != tt.id) {
+			t.Errorf("Expected authentication to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && client != nil {
+			t.Errorf("Expected authentication to fail (%d)", i)
+		}
+	}
+}
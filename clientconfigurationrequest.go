@@ -0,0 +1,198 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ClientConfigurationRequest is a request to read, update or delete a
+// registered client, normally sent to the registration_client_uri returned
+// by HandleRegistrationRequest.
+//
+// See http://tools.ietf.org/html/rfc7592
+type ClientConfigurationRequest struct {
+	// Method is the HTTP method of the request ("GET", "PUT" or "DELETE").
+	Method string
+
+	// Client is the client being managed.
+	Client Client
+
+	// Metadata is the validated client metadata from an update request.
+	Metadata ClientMetadata
+
+	// RotateSecret toggles if a new client secret should be issued on
+	// update.
+	RotateSecret bool
+
+	// HttpRequest *http.Request for special use
+	HttpRequest *http.Request
+}
+
+// registrationClientURI returns the client configuration endpoint URI for the
+// client.
+func (s *Server) registrationClientURI(client Client) string {
+	if s.Config.Endpoints.Registration == "" {
+		return ""
+	}
+	return strings.TrimRight(s.Config.Endpoints.Registration, "/") + "/" + url.PathEscape(client.GetID())
+}
+
+// getClientMetadata returns the registered client metadata, or metadata built
+// from the client's redirect uri if the client does not provide any.
+func getClientMetadata(client Client, sep string) *ClientMetadata {
+	if c, ok := client.(ClientMetadataGetter); ok {
+		md := *c.GetMetadata()
+		return &md
+	}
+
+	uris := []string{client.GetRedirectURI()}
+	if sep != "" {
+		uris = strings.Split(client.GetRedirectURI(), sep)
+	}
+	return &ClientMetadata{RedirectURIs: uris}
+}
+
+// HandleClientConfigurationRequest is the http.HandlerFunc for handling
+// client configuration requests. The client id is the last path component of
+// the request URL.
+func (s *Server) HandleClientConfigurationRequest(w *Response, r *http.Request) *ClientConfigurationRequest {
+	switch r.Method {
+	case "GET", "PUT", "DELETE":
+	default:
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be GET, PUT or DELETE")
+		return nil
+	}
+
+	// get registration access token
+	bearer := s.checkBearerAuth(r)
+	if bearer == nil || bearer.Code == "" {
+		w.SetError(ErrInvalidToken)
+		w.InternalError = errors.New("registration access token not sent")
+		return nil
+	}
+
+	ret := &ClientConfigurationRequest{
+		Method:      r.Method,
+		HttpRequest: r,
+	}
+
+	// unknown clients and invalid tokens are indistinguishable, see:
+	// http://tools.ietf.org/html/rfc7592#section-2
+	client, err := w.Storage.GetClient(path.Base(r.URL.Path))
+	if err != nil || client == nil {
+		w.SetError(ErrInvalidToken)
+		w.InternalError = err
+		return nil
+	}
+	if c, ok := client.(ClientRegistrationTokenMatcher); !ok || !c.RegistrationAccessTokenMatches(bearer.Code) {
+		w.SetError(ErrInvalidToken)
+		w.InternalError = errors.New("invalid registration access token")
+		return nil
+	}
+	ret.Client = client
+
+	if r.Method != "PUT" {
+		return ret
+	}
+
+	// decode metadata
+	var body struct {
+		ClientMetadata
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.SetError(ErrInvalidClientMetadata)
+		w.InternalError = err
+		return nil
+	}
+
+	// client id must match, and client secret must match if sent
+	if body.ClientID != client.GetID() {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("client id does not match")
+		return nil
+	}
+	if body.ClientSecret != "" && !clientSecretMatches(client, body.ClientSecret) {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("client secret does not match")
+		return nil
+	}
+
+	// validate metadata
+	ret.Metadata = body.ClientMetadata
+	if e, err := s.validateClientMetadata(&ret.Metadata); e != nil {
+		w.SetError(e)
+		w.InternalError = err
+		return nil
+	}
+
+	return ret
+}
+
+// FinishClientConfigurationRequest finalizes the request handled by
+// HandleClientConfigurationRequest.
+//
+// Deleting a client does not revoke its tokens, as storage does not provide a
+// way to look up tokens by client.
+func (s *Server) FinishClientConfigurationRequest(w *Response, r *http.Request, cr *ClientConfigurationRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	switch cr.Method {
+	case "GET":
+		w.Output = s.clientInformation(cr.Client, getClientMetadata(cr.Client, s.Config.RedirectURISeparator))
+
+	case "PUT":
+		prev, ok := cr.Client.(*DefaultClient)
+		if !ok {
+			w.SetError(ErrServerError)
+			w.InternalError = errors.New("client cannot be updated")
+			return
+		}
+
+		client := *prev
+		client.RedirectURI = strings.Join(cr.Metadata.RedirectURIs, s.Config.RedirectURISeparator)
+		client.Metadata = cr.Metadata
+
+		// generate new client secret, public clients have no secret
+		switch {
+		case cr.Metadata.TokenEndpointAuthMethod == ClientAuthNone:
+			client.Secret = ""
+		case cr.RotateSecret || client.Secret == "":
+			_, secret, err := s.ClientCredentialsGen.GenerateClientCredentials(&cr.Metadata)
+			if err != nil {
+				w.SetError(ErrServerError)
+				w.InternalError = err
+				return
+			}
+			client.Secret = secret
+		}
+
+		// save client
+		if err := w.Storage.SetClient(client.ID, &client); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return
+		}
+		cr.Client = &client
+
+		w.Output = s.clientInformation(cr.Client, &cr.Metadata)
+
+	case "DELETE":
+		if err := w.Storage.RemoveClient(cr.Client.GetID()); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return
+		}
+
+		w.StatusCode = http.StatusNoContent
+	}
+}
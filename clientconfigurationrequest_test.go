@@ -0,0 +1,149 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newClientConfigurationTestServer(t *testing.T) (*Server, *MemStorage) {
+	sconfig := NewConfig()
+	sconfig.Endpoints.Registration = "http://localhost:14000/register"
+	storage := NewTestStorage(t)
+	err := storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		Secret:      "eeffgghh",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			RedirectURIs: []string{"http://localhost:14000/otherauth"},
+		},
+		RegistrationAccessToken: "reg5678",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	server.ClientCredentialsGen = &TestingClientCredentialsGen{}
+	return server, storage
+}
+
+func TestClientConfigurationRead(t *testing.T) {
+	server, _ := newClientConfigurationTestServer(t)
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000/register/5678", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer reg5678")
+
+	if cr := server.HandleClientConfigurationRequest(resp, req); cr != nil {
+		server.FinishClientConfigurationRequest(resp, req, cr)
+	}
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	if d := resp.Output["registration_client_uri"]; d != "http://localhost:14000/register/5678" {
+		t.Fatalf("Unexpected registration client uri: %s", d)
+	}
+}
+
+func TestClientConfigurationInvalidToken(t *testing.T) {
+	server, _ := newClientConfigurationTestServer(t)
+
+	// "1234" has no registration access token
+	for _, id := range []string{"5678", "1234", "unknown"} {
+		resp := server.NewResponse()
+
+		req, err := http.NewRequest("GET", "http://localhost:14000/register/"+id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer invalid")
+
+		if cr := server.HandleClientConfigurationRequest(resp, req); cr != nil {
+			t.Fatalf("Request for %s with invalid token should have failed", id)
+		}
+
+		if resp.ErrorType != ErrInvalidToken.Type {
+			t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+		}
+	}
+}
+
+func TestClientConfigurationUpdate(t *testing.T) {
+	server, storage := newClientConfigurationTestServer(t)
+	resp := server.NewResponse()
+
+	body := `{"client_id":"5678","redirect_uris":["http://localhost:14000/newauth"]}`
+	req, err := http.NewRequest("PUT", "http://localhost:14000/register/5678", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer reg5678")
+
+	if cr := server.HandleClientConfigurationRequest(resp, req); cr != nil {
+		cr.RotateSecret = true
+		server.FinishClientConfigurationRequest(resp, req, cr)
+	}
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	client, err := storage.GetClient("5678")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.GetRedirectURI() != "http://localhost:14000/newauth" {
+		t.Fatalf("Unexpected redirect uri: %s", client.GetRedirectURI())
+	}
+
+	if client.GetSecret() != "secret" {
+		t.Fatalf("Client secret should have been rotated")
+	}
+}
+
+func TestClientConfigurationDelete(t *testing.T) {
+	server, storage := newClientConfigurationTestServer(t)
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("DELETE", "http://localhost:14000/register/5678", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer reg5678")
+
+	if cr := server.HandleClientConfigurationRequest(resp, req); cr != nil {
+		server.FinishClientConfigurationRequest(resp, req, cr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	if _, err := storage.GetClient("5678"); err == nil {
+		t.Fatalf("Client should have been deleted")
+	}
+
+	w := httptest.NewRecorder()
+	if err := WriteJSON(w, resp); err != nil {
+		t.Fatalf("Error writing json: %v", err)
+	}
+
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("Unexpected delete response: %d %q", w.Code, w.Body.String())
+	}
+}
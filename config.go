@@ -2,20 +2,134 @@ package oauthlib
 
 import "net/http"
 
+// Endpoints contains the absolute URLs of the server endpoints, as advertised
+// in the server metadata.
+type Endpoints struct {
+	// Authorization endpoint (HandleAuthRequest)
+	Authorization string
+
+	// Token endpoint (HandleTokenRequest)
+	Token string
+
+	// Revocation endpoint (HandleRevocationRequest)
+	Revocation string
+
+	// Introspection endpoint (HandleIntrospectionRequest)
+	Introspection string
+
+	// Client registration endpoint (HandleRegistrationRequest)
+	Registration string
+
+	// Device authorization endpoint (HandleDeviceAuthorizationRequest)
+	DeviceAuthorization string
+
+	// Pushed authorization request endpoint (HandlePushedAuthRequest)
+	PushedAuthorization string
+
+	// UserInfo endpoint (HandleUserInfoRequest)
+	UserInfo string
+
+	// JSON Web Key Set of the server signing keys (HandleJWKSRequest)
+	JWKS string
+
+	// Backchannel authentication endpoint
+	// (HandleBackchannelAuthenticationRequest)
+	BackchannelAuthentication string
+
+	// End-session endpoint (HandleLogoutRequest)
+	EndSession string
+
+	// Device verification endpoint (HandleDeviceVerificationRequest), shown
+	// to the user as the verification_uri
+	DeviceVerification string
+}
+
 // Config contains server configuration information
 type Config struct {
+	// Issuer identifier URL of the server, as advertised in the server
+	// metadata
+	Issuer string
+
+	// Endpoint URLs, as advertised in the server metadata
+	Endpoints Endpoints
+
+	// List of scopes advertised in the server metadata. If not blank, client
+	// registration is restricted to these scopes.
+	Scopes []string
+
+	// Require an initial access token for client registration
+	RequireInitialAccessToken bool
+
+	// List of the resource indicators (absolute URIs) of the known resource
+	// servers, the resources tokens can be restricted to with the "resource"
+	// parameter. If blank, any resource URI is accepted.
+	Resources []string
+
+	// Keys of the issuers trusted to issue JWT authorization grants, by
+	// issuer ("iss")
+	TrustedIssuers map[string]*JSONWebKeySet
+
 	// Authorization token expiration in seconds (default 5 minutes)
 	AuthorizationExpiration int32
 
 	// Access token expiration in seconds (default 1 hour)
 	AccessExpiration int32
 
+	// ID token expiration in seconds (default 1 hour)
+	IDTokenExpiration int32
+
+	// Expiration in seconds of the signed authorization responses of the JWT
+	// response modes (default 10 minutes)
+	JWTResponseExpiration int32
+
+	// Refresh token expiration in seconds for public clients, counted from
+	// the issue of the refresh token (default 1 day). Refresh tokens are
+	// rotated on use, so an active public client keeps refreshing. Zero
+	// disables expiration.
+	PublicRefreshExpiration int32
+
+	// Pushed authorization request uri expiration in seconds (default 1
+	// minute)
+	PushedAuthorizationExpiration int32
+
+	// Require all authorization requests to be pushed
+	RequirePushedAuthorizationRequests bool
+
+	// Require all authorization requests to be sent as signed request
+	// objects
+	RequireSignedRequestObject bool
+
+	// Device code expiration in seconds (default 10 minutes)
+	DeviceExpiration int32
+
+	// Minimum device token request polling interval in seconds (default 5
+	// seconds)
+	DeviceInterval int32
+
+	// Backchannel authentication request id expiration in seconds (default
+	// 5 minutes)
+	BackchannelExpiration int32
+
+	// Minimum backchannel authentication polling interval in seconds
+	// (default 5 seconds)
+	BackchannelInterval int32
+
+	// Back-channel logout token expiration in seconds (default 2 minutes)
+	LogoutTokenExpiration int32
+
 	// Token type to return
 	TokenType string
 
-	// List of allowed authorize types ("code" or "token" only)
+	// List of allowed authorize types ("code", "token", "id_token", or the
+	// combinations "code id_token", "code token", "id_token token" and
+	// "code id_token token")
 	AllowedAuthRequestTypes []string
 
+	// List of allowed authorization response modes ("query", "fragment",
+	// "form_post", "query.jwt", "fragment.jwt" or "form_post.jwt"). The JWT
+	// modes require a server signing key.
+	AllowedResponseModes []string
+
 	// List of allowed access types (only AuthorizationCodeGrant by default)
 	AllowedGrantTypes []GrantType
 
@@ -26,13 +140,30 @@ type Config struct {
 	// Separator to support multiple URIs in Client.GetRedirectURI().
 	// If blank (the default), don't allow multiple URIs.
 	RedirectURISeparator string
+
+	// When authorization requests must include a PKCE code challenge
+	// (default PKCEOptional)
+	RequirePKCE PKCERequirement
+
+	// List of allowed PKCE code challenge methods ("plain" or "S256")
+	AllowedCodeChallengeMethods []string
+
+	// Bind access tokens to the mutual TLS client certificate of all
+	// clients. If false, only tokens of clients registered with
+	// tls_client_certificate_bound_access_tokens are bound.
+	CertificateBoundAccessTokens bool
 }
 
 // isAuthRequestTypeAllowed determines if the passed AuthorizedRequestType
-// is in the Config.AllowedAuthRequestTypes
+// is in the Config.AllowedAuthRequestTypes. The order of the values of
+// combined response types does not matter.
 func (c Config) isAuthRequestTypeAllowed(at string) bool {
+	at = normalizeResponseType(at)
+	if at == "" {
+		return false
+	}
 	for _, k := range c.AllowedAuthRequestTypes {
-		if k == at {
+		if normalizeResponseType(k) == at {
 			return true
 		}
 	}
@@ -53,11 +184,30 @@ func (c Config) isGrantTypeAllowed(gt GrantType) bool {
 // NewConfig returns a new Config with default configuration
 func NewConfig() *Config {
 	return &Config{
-		AuthorizationExpiration: 250,
-		AccessExpiration:        3600,
-		TokenType:               "Bearer",
-		AllowedAuthRequestTypes: []string{"code"},
-		AllowedGrantTypes:       []GrantType{AuthorizationCodeGrant},
-		HttpStatusCode:          http.StatusOK,
+		AuthorizationExpiration:       250,
+		AccessExpiration:              3600,
+		IDTokenExpiration:             3600,
+		JWTResponseExpiration:         600,
+		PublicRefreshExpiration:       86400,
+		PushedAuthorizationExpiration: 60,
+		DeviceExpiration:              600,
+		DeviceInterval:                5,
+		BackchannelExpiration:         300,
+		BackchannelInterval:           5,
+		LogoutTokenExpiration:         120,
+		TokenType:                     "Bearer",
+		AllowedAuthRequestTypes:       []string{"code"},
+		AllowedResponseModes: []string{
+			ResponseModeQuery,
+			ResponseModeFragment,
+			ResponseModeFormPost,
+		},
+		AllowedGrantTypes: []GrantType{AuthorizationCodeGrant},
+		HttpStatusCode:    http.StatusOK,
+		RequirePKCE:       PKCEOptional,
+		AllowedCodeChallengeMethods: []string{
+			PKCEMethodS256,
+			PKCEMethodPlain,
+		},
 	}
 }
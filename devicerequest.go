@@ -0,0 +1,410 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DeviceAuthorizationStatus is the status of a device authorization.
+type DeviceAuthorizationStatus int
+
+const (
+	// DevicePending is the status of a device authorization awaiting user
+	// interaction.
+	DevicePending DeviceAuthorizationStatus = iota
+
+	// DeviceApproved is the status of a device authorization approved by the
+	// user.
+	DeviceApproved
+
+	// DeviceDenied is the status of a device authorization denied by the
+	// user.
+	DeviceDenied
+)
+
+// DeviceAuthorization is a pending device authorization.
+//
+// See http://tools.ietf.org/html/rfc8628
+type DeviceAuthorization struct {
+	// Client information.
+	Client Client
+
+	// DeviceCode is the device verification code.
+	DeviceCode string
+
+	// UserCode is the end-user verification code.
+	UserCode string
+
+	// Scope is the requested scope.
+	Scope string
+
+	// AuthorizationDetails are the requested authorization details. Can be
+	// nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// Status is the status of the authorization.
+	Status DeviceAuthorizationStatus
+
+	// Subject is the end-user that approved the authorization.
+	Subject string
+
+	// AuthTime is the time the end-user authenticated.
+	AuthTime time.Time
+
+	// ExpiresIn is the device code expiration in seconds.
+	ExpiresIn int32
+
+	// Interval is the minimum polling interval in seconds.
+	Interval int32
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+
+	// PolledAt is the time of the last token request.
+	PolledAt time.Time
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+}
+
+// IsExpiredAt is true if the device authorization expires at time 't'
+func (d *DeviceAuthorization) IsExpiredAt(t time.Time) bool {
+	return d.ExpireAt().Before(t)
+}
+
+// ExpireAt returns the expiration date.
+func (d *DeviceAuthorization) ExpireAt() time.Time {
+	return d.CreatedAt.Add(time.Duration(d.ExpiresIn) * time.Second)
+}
+
+// DeviceCodeGen is the device code generator interface.
+type DeviceCodeGen interface {
+	GenerateDeviceCode(data *DeviceAuthorization) (devicecode string, usercode string, err error)
+}
+
+// normalizeUserCode normalizes a user code entered by the user, removing
+// separators and converting to upper case.
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// getDeviceStorage returns the response storage as DeviceStorage. Sets an
+// error on the response if the storage does not support device
+// authorizations.
+func getDeviceStorage(w *Response) DeviceStorage {
+	ds, ok := w.Storage.(DeviceStorage)
+	if !ok {
+		w.SetError(ErrServerError)
+		w.InternalError = errors.New("storage does not support device authorization")
+		return nil
+	}
+	return ds
+}
+
+// HandleDeviceAuthorizationRequest is the http.HandlerFunc for handling
+// device authorization requests.
+func (s *Server) HandleDeviceAuthorizationRequest(w *Response, r *http.Request) *DeviceAuthorization {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	if !s.Config.isGrantTypeAllowed(DeviceCodeGrant) {
+		w.SetError(ErrUnauthorizedClient)
+		return nil
+	}
+
+	ret := &DeviceAuthorization{
+		Scope:     r.Form.Get("scope"),
+		Status:    DevicePending,
+		ExpiresIn: s.Config.DeviceExpiration,
+		Interval:  s.Config.DeviceInterval,
+		CreatedAt: s.Now(),
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// authorization details must be of supported types
+	if ret.AuthorizationDetails, err = parseAuthorizationDetails(r.Form.Get("authorization_details")); err == nil {
+		err = s.validateAuthorizationDetails(ret.Client, ret.AuthorizationDetails)
+	}
+	if err != nil {
+		w.SetError(ErrInvalidAuthorizationDetails)
+		w.InternalError = err
+		return nil
+	}
+
+	return ret
+}
+
+// FinishDeviceAuthorizationRequest finalizes the request handled by
+// HandleDeviceAuthorizationRequest, saving the pending device authorization.
+func (s *Server) FinishDeviceAuthorizationRequest(w *Response, r *http.Request, da *DeviceAuthorization) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	ds := getDeviceStorage(w)
+	if ds == nil {
+		return
+	}
+
+	// generate codes
+	var err error
+	da.DeviceCode, da.UserCode, err = s.DeviceCodeGen.GenerateDeviceCode(da)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+	da.UserCode = normalizeUserCode(da.UserCode)
+
+	// save device authorization
+	if err = ds.SaveDeviceAuthorization(da); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// output data
+	w.Output["device_code"] = da.DeviceCode
+	w.Output["user_code"] = da.UserCode
+	w.Output["verification_uri"] = s.Config.Endpoints.DeviceVerification
+	if u, err := url.Parse(s.Config.Endpoints.DeviceVerification); err == nil && u.Host != "" {
+		q := u.Query()
+		q.Set("user_code", da.UserCode)
+		u.RawQuery = q.Encode()
+		w.Output["verification_uri_complete"] = u.String()
+	}
+	w.Output["expires_in"] = da.ExpiresIn
+	w.Output["interval"] = da.Interval
+}
+
+// DeviceVerificationRequest is the end-user verification of a device
+// authorization, normally sent to the verification_uri on the server.
+type DeviceVerificationRequest struct {
+	// UserCode is the user code passed in the request.
+	UserCode string
+
+	// DeviceAuthorization is the pending device authorization.
+	DeviceAuthorization *DeviceAuthorization
+
+	// Authorized toggles if request is authorized
+	Authorized bool
+
+	// Subject is the end-user that approved the authorization. Set before
+	// calling FinishDeviceVerificationRequest.
+	Subject string
+
+	// AuthTime is the time the end-user authenticated. Defaults to the time
+	// of FinishDeviceVerificationRequest if not set.
+	AuthTime time.Time
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+
+	// HttpRequest *http.Request for special use
+	HttpRequest *http.Request
+}
+
+// HandleDeviceVerificationRequest is the http.HandlerFunc for handling
+// end-user device verification requests.
+func (s *Server) HandleDeviceVerificationRequest(w *Response, r *http.Request) *DeviceVerificationRequest {
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	ds := getDeviceStorage(w)
+	if ds == nil {
+		return nil
+	}
+
+	ret := &DeviceVerificationRequest{
+		UserCode:    normalizeUserCode(r.Form.Get("user_code")),
+		Authorized:  false,
+		HttpRequest: r,
+	}
+
+	// "user_code" is required
+	if ret.UserCode == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must be a valid pending device authorization
+	ret.DeviceAuthorization, err = ds.LoadDeviceAuthorizationByUserCode(ret.UserCode)
+	if err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+	if ret.DeviceAuthorization == nil || ret.DeviceAuthorization.Client == nil {
+		w.SetError(ErrInvalidGrant)
+		return nil
+	}
+	if ret.DeviceAuthorization.Status != DevicePending {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("device authorization is not pending")
+		return nil
+	}
+	if ret.DeviceAuthorization.IsExpiredAt(s.Now()) {
+		w.SetError(ErrExpiredToken)
+		return nil
+	}
+
+	return ret
+}
+
+// FinishDeviceVerificationRequest finalizes the request handled by
+// HandleDeviceVerificationRequest, approving or denying the device
+// authorization.
+func (s *Server) FinishDeviceVerificationRequest(w *Response, r *http.Request, dr *DeviceVerificationRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	ds := getDeviceStorage(w)
+	if ds == nil {
+		return
+	}
+
+	da := dr.DeviceAuthorization
+	if dr.Authorized {
+		if dr.AuthTime.IsZero() {
+			dr.AuthTime = s.Now()
+		}
+		da.Status = DeviceApproved
+		da.Subject = dr.Subject
+		da.AuthTime = dr.AuthTime
+		da.UserData = dr.UserData
+	} else {
+		da.Status = DeviceDenied
+	}
+
+	// save device authorization
+	if err := ds.SaveDeviceAuthorization(da); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	if !dr.Authorized {
+		w.SetError(ErrAccessDenied)
+	}
+}
+
+func (s *Server) handleDeviceCodeRequest(w *Response, r *http.Request) *TokenRequest {
+	ds := getDeviceStorage(w)
+	if ds == nil {
+		return nil
+	}
+
+	// generate access token
+	ret := &TokenRequest{
+		GrantType:       DeviceCodeGrant,
+		Code:            r.Form.Get("device_code"),
+		GenerateRefresh: true,
+		Expiration:      s.Config.AccessExpiration,
+	}
+
+	// "device_code" is required
+	if ret.Code == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// must be a valid device code
+	var err error
+	ret.DeviceAuthorization, err = ds.LoadDeviceAuthorization(ret.Code)
+	if err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+	da := ret.DeviceAuthorization
+	if da == nil || da.Client == nil {
+		w.SetError(ErrInvalidGrant)
+		return nil
+	}
+
+	// device code must be from the client
+	if da.Client.GetID() != ret.Client.GetID() {
+		w.SetError(ErrInvalidGrant)
+		return nil
+	}
+
+	now := s.Now()
+	if da.IsExpiredAt(now) {
+		w.SetError(ErrExpiredToken)
+		return nil
+	}
+
+	switch da.Status {
+	case DeviceDenied:
+		if err = ds.RemoveDeviceAuthorization(da.DeviceCode); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return nil
+		}
+		w.SetError(ErrAccessDenied)
+		return nil
+
+	case DevicePending:
+		e := ErrAuthorizationPending
+
+		// polling too quickly, increase interval, see:
+		// http://tools.ietf.org/html/rfc8628#section-3.5
+		if !da.PolledAt.IsZero() && now.Sub(da.PolledAt) < time.Duration(da.Interval)*time.Second {
+			da.Interval += 5
+			e = ErrSlowDown
+		}
+		da.PolledAt = now
+
+		if err = ds.SaveDeviceAuthorization(da); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return nil
+		}
+		w.SetError(e)
+		return nil
+	}
+
+	// set rest of data
+	ret.Scope = da.Scope
+	ret.Subject = da.Subject
+	ret.AuthTime = da.AuthTime
+	ret.UserData = da.UserData
+	ret.RedirectURI = firstURI(ret.Client.GetRedirectURI(), s.Config.RedirectURISeparator)
+
+	return ret
+}
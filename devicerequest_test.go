@@ -0,0 +1,192 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Predictable testing device code generation
+type TestingDeviceCodeGen struct {
+}
+
+func (a *TestingDeviceCodeGen) GenerateDeviceCode(data *DeviceAuthorization) (devicecode string, usercode string, err error) {
+	return "d1", "bcdf-ghjk", nil
+}
+
+func newDeviceTokenRequest(t *testing.T) *http.Request {
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(DeviceCodeGrant))
+	req.Form.Set("device_code", "d1")
+	return req
+}
+
+func TestDeviceCode(t *testing.T) {
+	now := time.Now()
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{DeviceCodeGrant}
+	sconfig.Endpoints.DeviceVerification = "http://localhost:14000/device"
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+	server.DeviceCodeGen = &TestingDeviceCodeGen{}
+	server.Now = func() time.Time { return now }
+
+	// device authorization
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/device_authorization", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("scope", "everything")
+
+	if da := server.HandleDeviceAuthorizationRequest(resp, req); da != nil {
+		server.FinishDeviceAuthorizationRequest(resp, req, da)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["user_code"]; d != "BCDFGHJK" {
+		t.Fatalf("Unexpected user code: %s", d)
+	}
+
+	if d := resp.Output["verification_uri"]; d != "http://localhost:14000/device" {
+		t.Fatalf("Unexpected verification uri: %s", d)
+	}
+
+	// poll before user interaction, then too quickly
+	for _, e := range []*ResponseError{ErrAuthorizationPending, ErrSlowDown} {
+		resp = server.NewResponse()
+		req = newDeviceTokenRequest(t)
+
+		if tr := server.HandleTokenRequest(resp, req); tr != nil {
+			t.Fatalf("Token request should have failed with %s", e.Type)
+		}
+
+		if resp.ErrorType != e.Type {
+			t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+		}
+	}
+
+	// user verification
+	resp = server.NewResponse()
+	req, err = http.NewRequest("GET", "http://localhost:14000/device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("user_code", "bcdf-ghjk")
+
+	if dr := server.HandleDeviceVerificationRequest(resp, req); dr != nil {
+		dr.Authorized = true
+		dr.Subject = "user"
+		server.FinishDeviceVerificationRequest(resp, req, dr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	approvedAt := now
+
+	// poll after user approval
+	now = now.Add(time.Minute)
+	resp = server.NewResponse()
+	req = newDeviceTokenRequest(t)
+
+	if tr := server.HandleTokenRequest(resp, req); tr != nil {
+		if tr.Subject != "user" || !tr.AuthTime.Equal(approvedAt) {
+			t.Fatalf("Unexpected subject and auth time: %q %v", tr.Subject, tr.AuthTime)
+		}
+		tr.Authorized = true
+		server.FinishTokenRequest(resp, req, tr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["access_token"]; d != "1" {
+		t.Fatalf("Unexpected access token: %s", d)
+	}
+
+	if d := resp.Output["scope"]; d != "everything" {
+		t.Fatalf("Unexpected scope: %s", d)
+	}
+
+	if ag := server.Storage.(*MemStorage).AccessGrants["1"]; ag == nil || ag.Subject != "user" {
+		t.Fatalf("Token should be issued to the approving user: %v", ag)
+	}
+
+	// device code can only be used once
+	resp = server.NewResponse()
+	req = newDeviceTokenRequest(t)
+
+	if tr := server.HandleTokenRequest(resp, req); tr != nil {
+		t.Fatalf("Token request with used device code should have failed")
+	}
+}
+
+func TestDeviceCodeDenied(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{DeviceCodeGrant}
+	storage := NewTestStorage(t)
+	err := storage.SaveDeviceAuthorization(&DeviceAuthorization{
+		Client:     storage.Clients["1234"],
+		DeviceCode: "d1",
+		UserCode:   "BCDFGHJK",
+		Status:     DeviceDenied,
+		ExpiresIn:  600,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	resp := server.NewResponse()
+
+	if tr := server.HandleTokenRequest(resp, newDeviceTokenRequest(t)); tr != nil {
+		t.Fatalf("Token request for denied device authorization should have failed")
+	}
+
+	if resp.ErrorType != ErrAccessDenied.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+}
+
+func TestDeviceCodeExpired(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{DeviceCodeGrant}
+	storage := NewTestStorage(t)
+	err := storage.SaveDeviceAuthorization(&DeviceAuthorization{
+		Client:     storage.Clients["1234"],
+		DeviceCode: "d1",
+		UserCode:   "BCDFGHJK",
+		ExpiresIn:  600,
+		CreatedAt:  time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	resp := server.NewResponse()
+
+	if tr := server.HandleTokenRequest(resp, newDeviceTokenRequest(t)); tr != nil {
+		t.Fatalf("Token request for expired device code should have failed")
+	}
+
+	if resp.ErrorType != ErrExpiredToken.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+}
@@ -0,0 +1,317 @@
+package oauthlib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DPoPTokenType is the token type of access tokens bound to a DPoP key, see:
+// http://tools.ietf.org/html/rfc9449#section-5
+const DPoPTokenType = "DPoP"
+
+// dpopProofType is the "typ" header of DPoP proofs.
+const dpopProofType = "dpop+jwt"
+
+// dpopProofLifetime is how long after its "iat" claim a DPoP proof is
+// accepted.
+const dpopProofLifetime = 60 * time.Second
+
+// DPoPNonceGen generates and validates the nonces that clients must include
+// in their DPoP proofs, see:
+// http://tools.ietf.org/html/rfc9449#section-8
+type DPoPNonceGen interface {
+	// GenerateDPoPNonce returns a nonce to send to the client.
+	GenerateDPoPNonce() (string, error)
+
+	// DPoPNonceValid returns true if the nonce sent by the client is valid.
+	DPoPNonceValid(nonce string) bool
+}
+
+// DPoPNonceGenDefault is the default DPoP nonce generator. Nonces are the
+// time they were generated, authenticated with Key.
+type DPoPNonceGenDefault struct {
+	// Key is the HMAC key used to authenticate nonces.
+	Key []byte
+
+	// Expiration is how long a nonce is valid.
+	Expiration time.Duration
+
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+// NewDPoPNonceGenDefault creates a DPoP nonce generator using key, generating
+// nonces valid for 5 minutes.
+func NewDPoPNonceGenDefault(key []byte) *DPoPNonceGenDefault {
+	return &DPoPNonceGenDefault{
+		Key:        key,
+		Expiration: 5 * time.Minute,
+		Now:        time.Now,
+	}
+}
+
+// sign returns the nonce for the time ts.
+func (a *DPoPNonceGenDefault) sign(ts []byte) string {
+	mac := hmac.New(sha256.New, a.Key)
+	mac.Write(ts)
+	return b64(append(ts, mac.Sum(nil)...))
+}
+
+// GenerateDPoPNonce generates a nonce.
+func (a *DPoPNonceGenDefault) GenerateDPoPNonce() (string, error) {
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(a.Now().Unix()))
+	return a.sign(ts), nil
+}
+
+// DPoPNonceValid determines if the nonce was generated by a and has not
+// expired.
+func (a *DPoPNonceGenDefault) DPoPNonceValid(nonce string) bool {
+	buf, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil || len(buf) != 8+sha256.Size {
+		return false
+	}
+	if !hmac.Equal([]byte(a.sign(buf[:8])), []byte(nonce)) {
+		return false
+	}
+
+	issued := time.Unix(int64(binary.BigEndian.Uint64(buf[:8])), 0)
+	return !a.Now().After(issued.Add(a.Expiration))
+}
+
+// dpopSigningAlgs returns the signing algorithms supported for DPoP proofs.
+func (s *Server) dpopSigningAlgs() []string {
+	return []string{
+		AlgRS256, AlgRS384, AlgRS512, AlgPS256,
+		AlgES256, AlgES384, AlgES512,
+		AlgEdDSA,
+	}
+}
+
+// requestURL returns the URL of the request, without query and fragment.
+func requestURL(r *http.Request) string {
+	u := url.URL{
+		Scheme: r.URL.Scheme,
+		Host:   r.URL.Host,
+		Path:   r.URL.Path,
+	}
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if r.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	return u.String()
+}
+
+// htuMatches determines if the "htu" claim of a DPoP proof matches the URL
+// uri, ignoring query and fragment.
+func htuMatches(htu, uri string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
+
+// dpopProofKey returns the public key embedded in the "jwk" header of the
+// DPoP proof.
+func dpopProofKey(t *jwt) (*JSONWebKey, error) {
+	raw, ok := t.Header["jwk"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("dpop proof jwk required")
+	}
+	if _, ok = raw["d"]; ok {
+		return nil, errors.New("dpop proof jwk must not be a private key")
+	}
+
+	buf, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	key := new(JSONWebKey)
+	if err = key.UnmarshalJSON(buf); err != nil {
+		return nil, err
+	}
+	if _, ok = key.Key.([]byte); ok {
+		return nil, errors.New("dpop proof jwk must not be a symmetric key")
+	}
+
+	return key, nil
+}
+
+// verifyDPoPProof verifies the DPoP proof of the request for the URL htu,
+// returning the thumbprint of the proof key. If token is not blank, the proof
+// must be bound to the access token, see:
+// http://tools.ietf.org/html/rfc9449#section-4.3
+func (s *Server) verifyDPoPProof(storage Storage, r *http.Request, htu, token string) (string, *ResponseError, error) {
+	proofs := r.Header.Values("DPoP")
+	if len(proofs) != 1 {
+		return "", ErrInvalidDPoPProof, errors.New("exactly one dpop proof required")
+	}
+
+	t, err := parseJWT(proofs[0])
+	if err != nil {
+		return "", ErrInvalidDPoPProof, err
+	}
+	if t.typ() != dpopProofType {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof typ must be " + dpopProofType)
+	}
+
+	// check algorithm
+	supported := false
+	for _, alg := range s.dpopSigningAlgs() {
+		supported = supported || alg == t.alg()
+	}
+	if !supported {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof alg not supported: " + t.alg())
+	}
+
+	// verify signature with the embedded key
+	key, err := dpopProofKey(t)
+	if err != nil {
+		return "", ErrInvalidDPoPProof, err
+	}
+	if err = t.verify(key.Key); err != nil {
+		return "", ErrInvalidDPoPProof, err
+	}
+
+	// check claims
+	jti := t.Claims.String("jti")
+	if jti == "" {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof jti required")
+	}
+	if t.Claims.String("htm") != r.Method {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof htm does not match request method")
+	}
+	if !htuMatches(t.Claims.String("htu"), htu) {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof htu does not match request url")
+	}
+	iat, ok := t.Claims.Time("iat")
+	if !ok {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof iat required")
+	}
+	now := s.Now()
+	if now.Add(jwtLeeway).Before(iat) || now.After(iat.Add(dpopProofLifetime+jwtLeeway)) {
+		return "", ErrInvalidDPoPProof, errors.New("dpop proof iat out of range")
+	}
+	if token != "" {
+		hash := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare([]byte(t.Claims.String("ath")), []byte(b64(hash[:]))) != 1 {
+			return "", ErrInvalidDPoPProof, errors.New("dpop proof ath does not match access token")
+		}
+	}
+
+	// check server provided nonce
+	if s.DPoPNonceGen != nil && !s.DPoPNonceGen.DPoPNonceValid(t.Claims.String("nonce")) {
+		return "", ErrUseDPoPNonce, errors.New("dpop proof nonce invalid")
+	}
+
+	jkt, err := key.Thumbprint()
+	if err != nil {
+		return "", ErrInvalidDPoPProof, err
+	}
+
+	// proofs can only be used once
+	if err = saveJTI(storage, "dpop "+jkt+" "+jti, iat.Add(dpopProofLifetime+jwtLeeway)); err != nil {
+		return "", ErrInvalidDPoPProof, err
+	}
+
+	return jkt, nil, nil
+}
+
+// setDPoPNonce sets a new nonce in the DPoP-Nonce header of the response.
+func (s *Server) setDPoPNonce(w *Response) {
+	if s.DPoPNonceGen == nil {
+		return
+	}
+	if nonce, err := s.DPoPNonceGen.GenerateDPoPNonce(); err == nil {
+		w.Headers.Set("DPoP-Nonce", nonce)
+	}
+}
+
+// dpopBinding verifies the DPoP proof of the token request, returning the
+// thumbprint of the key that the tokens issued for the request are bound to,
+// or blank if the tokens are not bound. Sets an error on the response if the
+// proof is invalid or required but not sent.
+func (s *Server) dpopBinding(w *Response, r *http.Request, tr *TokenRequest) (string, bool) {
+	// refresh tokens of public clients are bound to the dpop key, see:
+	// http://tools.ietf.org/html/rfc9449#section-5
+	var bound []string
+	if tr.AccessGrant != nil && isPublicClient(tr.Client) && tr.AccessGrant.DPoPThumbprint != "" {
+		bound = append(bound, tr.AccessGrant.DPoPThumbprint)
+	}
+
+	// bound subject and actor tokens can only be exchanged with a proof of
+	// their dpop key
+	for _, et := range []*ExchangeToken{tr.SubjectToken, tr.ActorToken} {
+		if et != nil && et.AccessGrant != nil && et.AccessGrant.DPoPThumbprint != "" {
+			bound = append(bound, et.AccessGrant.DPoPThumbprint)
+		}
+	}
+
+	if len(r.Header.Values("DPoP")) == 0 {
+		required := len(bound) != 0
+		if c, ok := tr.Client.(ClientMetadataGetter); ok {
+			if md := c.GetMetadata(); md != nil && md.DPoPBoundAccessTokens {
+				required = true
+			}
+		}
+		if required {
+			w.SetError(ErrInvalidDPoPProof)
+			w.InternalError = errors.New("dpop proof required")
+			return "", false
+		}
+		return "", true
+	}
+
+	htu := s.Config.Endpoints.Token
+	if htu == "" {
+		htu = requestURL(r)
+	}
+	jkt, e, err := s.verifyDPoPProof(w.Storage, r, htu, "")
+	if e != nil {
+		w.SetError(e)
+		w.InternalError = err
+		if e == ErrUseDPoPNonce {
+			s.setDPoPNonce(w)
+		}
+		return "", false
+	}
+
+	for _, b := range bound {
+		if subtle.ConstantTimeCompare([]byte(jkt), []byte(b)) != 1 {
+			w.SetError(ErrInvalidGrant)
+			w.InternalError = errors.New("dpop proof key does not match token binding")
+			return "", false
+		}
+	}
+
+	return jkt, true
+}
+
+// tokenType returns the token type of the access grant.
+func (s *Server) tokenType(ag *AccessGrant) string {
+	if ag.DPoPThumbprint != "" {
+		return DPoPTokenType
+	}
+	return s.Config.TokenType
+}
@@ -0,0 +1,253 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testDPoPJTI int
+
+// newTestDPoPProof creates a DPoP proof signed with key. If token is not
+// blank, the proof is bound to the access token.
+func newTestDPoPProof(t *testing.T, key *ecdsa.PrivateKey, htm, htu, token, nonce string) string {
+	testDPoPJTI++
+	claims := JWTClaims{
+		"jti": strconv.Itoa(testDPoPJTI),
+		"htm": htm,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+	}
+	if token != "" {
+		hash := sha256.Sum256([]byte(token))
+		claims["ath"] = b64(hash[:])
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	header := map[string]interface{}{
+		"typ": dpopProofType,
+		"jwk": JSONWebKey{Key: &key.PublicKey},
+	}
+	proof, err := signJWT(key, AlgES256, header, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestDPoPBoundAccessToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{ClientCredentialsGrant}
+	storage := NewTestStorage(t)
+	server := NewServer(sconfig, storage)
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	// issue token
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Header.Set("DPoP", newTestDPoPProof(t, key, "POST", "http://localhost:14000/token", "", ""))
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(ClientCredentialsGrant))
+
+	if ar := server.HandleTokenRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishTokenRequest(resp, req, ar)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["token_type"]; d != DPoPTokenType {
+		t.Fatalf("Unexpected token type: %s", d)
+	}
+
+	jkt, err := JSONWebKey{Key: &key.PublicKey}.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag, err := storage.LoadAccessGrant("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ag.DPoPThumbprint != jkt {
+		t.Fatalf("Unexpected dpop thumbprint: %s", ag.DPoPThumbprint)
+	}
+
+	// present token
+	replayed := newTestDPoPProof(t, key, "GET", "http://localhost:14000/resource", "1", "")
+	var tests = []struct {
+		scheme string
+		proof  string
+		valid  bool
+	}{
+		{"DPoP", replayed, true},
+		{"DPoP", replayed, false},
+		{"Bearer", newTestDPoPProof(t, key, "GET", "http://localhost:14000/resource", "1", ""), false},
+		{"DPoP", newTestDPoPProof(t, otherKey, "GET", "http://localhost:14000/resource", "1", ""), false},
+		{"DPoP", newTestDPoPProof(t, key, "POST", "http://localhost:14000/resource", "1", ""), false},
+		{"DPoP", newTestDPoPProof(t, key, "GET", "http://localhost:14000/other", "1", ""), false},
+		{"DPoP", newTestDPoPProof(t, key, "GET", "http://localhost:14000/resource", "2", ""), false},
+		{"DPoP", "", false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/resource?a=b", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", tt.scheme+" 1")
+		if tt.proof != "" {
+			req.Header.Set("DPoP", tt.proof)
+		}
+
+		rr := server.HandleResourceRequest(resp, req, "http://localhost:14000/resource")
+		if tt.valid && rr == nil {
+			t.Errorf("Expected resource request to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && (rr != nil || resp.StatusCode != http.StatusUnauthorized) {
+			t.Errorf("Expected resource request to fail (%d)", i)
+		}
+	}
+}
+
+func TestDPoPNonce(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{ClientCredentialsGrant}
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+	server.DPoPNonceGen = NewDPoPNonceGenDefault([]byte("secret"))
+
+	tokenRequest := func(nonce string) *Response {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Header.Set("DPoP", newTestDPoPProof(t, key, "POST", "http://localhost:14000/token", "", nonce))
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("grant_type", string(ClientCredentialsGrant))
+
+		if ar := server.HandleTokenRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			server.FinishTokenRequest(resp, req, ar)
+		}
+		return resp
+	}
+
+	// without nonce
+	resp := tokenRequest("")
+	if resp.ErrorType != ErrUseDPoPNonce.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+	nonce := resp.Headers.Get("DPoP-Nonce")
+	if nonce == "" {
+		t.Fatalf("Expected DPoP-Nonce header")
+	}
+
+	// with nonce
+	resp = tokenRequest(nonce)
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	// modified nonce
+	gen := server.DPoPNonceGen.(*DPoPNonceGenDefault)
+	if gen.DPoPNonceValid(nonce[:len(nonce)-2] + "AA") {
+		t.Fatalf("Modified nonce should be invalid")
+	}
+
+	// expired nonce
+	gen.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	if gen.DPoPNonceValid(nonce) {
+		t.Fatalf("Nonce should have expired")
+	}
+}
+
+func TestDPoPBoundInfoRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jkt, err := JSONWebKey{Key: &key.PublicKey}.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := NewTestStorage(t)
+	err = storage.SaveAccessGrant(&AccessGrant{
+		Client:         storage.Clients["1234"],
+		AccessToken:    "dpop",
+		ExpiresIn:      3600,
+		CreatedAt:      time.Now(),
+		DPoPThumbprint: jkt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(NewConfig(), storage)
+
+	var tests = []struct {
+		authorization string
+		proof         string
+		valid         bool
+	}{
+		{"DPoP dpop", newTestDPoPProof(t, key, "GET", "http://localhost:14000/info", "dpop", ""), true},
+		{"Bearer dpop", newTestDPoPProof(t, key, "GET", "http://localhost:14000/info", "dpop", ""), false},
+		{"DPoP dpop", newTestDPoPProof(t, otherKey, "GET", "http://localhost:14000/info", "dpop", ""), false},
+		{"DPoP dpop", "", false},
+		{"DPoP 9999", newTestDPoPProof(t, key, "GET", "http://localhost:14000/info", "9999", ""), false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/info", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", tt.authorization)
+		if tt.proof != "" {
+			req.Header.Set("DPoP", tt.proof)
+		}
+
+		ir := server.HandleInfoRequest(resp, req)
+		if tt.valid && ir == nil {
+			t.Errorf("Expected info request to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && (ir != nil || !resp.IsError) {
+			t.Errorf("Expected info request to fail (%d)", i)
+		}
+	}
+}
@@ -117,3 +117,166 @@ var (
 		Desc:  "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method).",
 	}
 )
+
+// Bearer token errors, see:
+// http://tools.ietf.org/html/rfc6750#section-3.1
+var (
+	// ErrInvalidToken is the error when the provided access token is invalid.
+	ErrInvalidToken = &ResponseError{
+		Code:  http.StatusUnauthorized,
+		Type:  "invalid_token",
+		Title: "Invalid Token",
+		Desc:  "The access token provided is expired, revoked, malformed, or invalid for other reasons.",
+	}
+
+	// ErrInsufficientScope is the error when the access token does not have
+	// the scope required by the request.
+	ErrInsufficientScope = &ResponseError{
+		Code:  http.StatusForbidden,
+		Type:  "insufficient_scope",
+		Title: "Insufficient Scope",
+		Desc:  "The request requires higher privileges than provided by the access token.",
+	}
+)
+
+// Client registration errors, see:
+// http://tools.ietf.org/html/rfc7591#section-3.2.2
+var (
+	// ErrInvalidRedirectURI is the error when a redirect uri in the client
+	// metadata is invalid.
+	ErrInvalidRedirectURI = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_redirect_uri",
+		Title: "Invalid Redirect URI",
+		Desc:  "The value of one or more redirection URIs is invalid.",
+	}
+
+	// ErrInvalidClientMetadata is the error when a field in the client
+	// metadata is invalid.
+	ErrInvalidClientMetadata = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_client_metadata",
+		Title: "Invalid Client Metadata",
+		Desc:  "The value of one of the client metadata fields is invalid and the server has rejected this request.",
+	}
+)
+
+// Device authorization grant errors, see:
+// http://tools.ietf.org/html/rfc8628#section-3.5
+var (
+	// ErrAuthorizationPending is the error when the device authorization
+	// request is still pending.
+	ErrAuthorizationPending = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "authorization_pending",
+		Title: "Authorization Pending",
+		Desc:  "The authorization request is still pending as the end user hasn't yet completed the user-interaction steps.",
+	}
+
+	// ErrSlowDown is the error when the client is polling too quickly.
+	ErrSlowDown = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "slow_down",
+		Title: "Slow Down",
+		Desc:  "The authorization request is still pending and polling should continue, but the interval must be increased by 5 seconds for this and all subsequent requests.",
+	}
+
+	// ErrExpiredToken is the error when the device code has expired.
+	ErrExpiredToken = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "expired_token",
+		Title: "Expired Token",
+		Desc:  "The device code has expired, and the device authorization session has concluded.",
+	}
+)
+
+// Client initiated backchannel authentication errors, see:
+// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.13
+var (
+	// ErrUnknownUserID is the error when the end-user of the backchannel
+	// authentication request could not be identified from the hint.
+	ErrUnknownUserID = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "unknown_user_id",
+		Title: "Unknown User ID",
+		Desc:  "The OpenID Provider is not able to identify which end-user the Client wishes to be authenticated by means of the hint provided in the request.",
+	}
+)
+
+// DPoP errors, see:
+// http://tools.ietf.org/html/rfc9449#section-12.2
+var (
+	// ErrInvalidDPoPProof is the error when the DPoP proof is invalid.
+	ErrInvalidDPoPProof = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_dpop_proof",
+		Title: "Invalid DPoP Proof",
+		Desc:  "The DPoP proof is missing, malformed, or invalid.",
+	}
+
+	// ErrUseDPoPNonce is the error when the DPoP proof does not include the
+	// nonce provided by the server.
+	ErrUseDPoPNonce = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "use_dpop_nonce",
+		Title: "Use DPoP Nonce",
+		Desc:  "The DPoP proof must include the nonce provided by the server in the DPoP-Nonce header.",
+	}
+)
+
+// Resource indicator errors, see:
+// http://tools.ietf.org/html/rfc8707#section-2
+var (
+	// ErrInvalidTarget is the error when a requested resource is invalid,
+	// unknown, or not authorized for the client.
+	ErrInvalidTarget = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_target",
+		Title: "Invalid Target",
+		Desc:  "The requested resource is invalid, missing, unknown, or malformed.",
+	}
+)
+
+// Rich authorization request errors, see:
+// http://tools.ietf.org/html/rfc9396#section-5
+var (
+	// ErrInvalidAuthorizationDetails is the error when the requested
+	// authorization details are invalid or not allowed.
+	ErrInvalidAuthorizationDetails = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_authorization_details",
+		Title: "Invalid Authorization Details",
+		Desc:  "The authorization details are invalid, of an unknown type, or not allowed for the client.",
+	}
+)
+
+// Authorization request object errors, see:
+// http://tools.ietf.org/html/rfc9101#section-6.3
+var (
+	// ErrInvalidRequestURI is the error when the request_uri of the
+	// authorization request is invalid or expired.
+	ErrInvalidRequestURI = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_request_uri",
+		Title: "Invalid Request URI",
+		Desc:  "The request_uri in the authorization request returns an error or contains invalid data.",
+	}
+
+	// ErrInvalidRequestObject is the error when the request object of the
+	// authorization request is invalid.
+	ErrInvalidRequestObject = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "invalid_request_object",
+		Title: "Invalid Request Object",
+		Desc:  "The request parameter contains an invalid request object.",
+	}
+
+	// ErrRequestURINotSupported is the error when request objects can't be
+	// passed by reference.
+	ErrRequestURINotSupported = &ResponseError{
+		Code:  http.StatusBadRequest,
+		Type:  "request_uri_not_supported",
+		Title: "Request URI Not Supported",
+		Desc:  "The authorization server does not support use of the request_uri parameter.",
+	}
+)
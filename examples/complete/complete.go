@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/kenshaw/oauthlib"
 	"github.com/kenshaw/oauthlib/oauthlibtest"
@@ -22,8 +23,27 @@ func main() {
 		oauthlib.ClientCredentialsGrant,
 		oauthlib.AssertionGrant,
 	}
+	sconfig.Issuer = "http://localhost:14000"
+	sconfig.Endpoints = oauthlib.Endpoints{
+		Authorization: "http://localhost:14000/authorize",
+		Token:         "http://localhost:14000/token",
+		Revocation:    "http://localhost:14000/revoke",
+		Introspection: "http://localhost:14000/introspect",
+
+		PushedAuthorization: "http://localhost:14000/par",
+		UserInfo:            "http://localhost:14000/userinfo",
+		JWKS:                "http://localhost:14000" + oauthlib.JWKSPath,
+		EndSession:          "http://localhost:14000/logout",
+	}
 	server := oauthlib.NewServer(sconfig, oauthlib.NewTestStorage(nil))
 
+	// rotate signing keys daily
+	keySet, err := oauthlib.NewKeySet(nil, 24*time.Hour)
+	if err != nil {
+		panic(err)
+	}
+	server.KeySet = keySet
+
 	// Authorization code endpoint
 	http.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
 		resp := server.NewResponse()
@@ -88,6 +108,91 @@ func main() {
 		oauthlib.WriteJSON(w, resp)
 	})
 
+	// Introspection endpoint
+	http.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+
+		if ir := server.HandleIntrospectionRequest(resp, r); ir != nil {
+			server.FinishIntrospectionRequest(resp, r, ir)
+		}
+		if resp.IsError && resp.InternalError != nil {
+			fmt.Printf("ERROR: %s\n", resp.InternalError)
+		}
+
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// Revocation endpoint
+	http.HandleFunc("/revoke", func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+
+		if rr := server.HandleRevocationRequest(resp, r); rr != nil {
+			server.FinishRevocationRequest(resp, r, rr)
+		}
+		if resp.IsError && resp.InternalError != nil {
+			fmt.Printf("ERROR: %s\n", resp.InternalError)
+		}
+
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// Pushed authorization request endpoint
+	http.HandleFunc("/par", func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+
+		if pa := server.HandlePushedAuthRequest(resp, r); pa != nil {
+			server.FinishPushedAuthRequest(resp, r, pa)
+		}
+		if resp.IsError && resp.InternalError != nil {
+			fmt.Printf("ERROR: %s\n", resp.InternalError)
+		}
+
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// UserInfo endpoint
+	http.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+
+		if ur := server.HandleUserInfoRequest(resp, r); ur != nil {
+			server.FinishUserInfoRequest(resp, r, ur)
+		}
+		if resp.IsError && resp.InternalError != nil {
+			fmt.Printf("ERROR: %s\n", resp.InternalError)
+		}
+
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// End-session endpoint
+	http.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+
+		if lr := server.HandleLogoutRequest(resp, r); lr != nil {
+			lr.Authorized = true
+			server.FinishLogoutRequest(resp, r, lr)
+		}
+		if resp.InternalError != nil {
+			fmt.Printf("ERROR: %s\n", resp.InternalError)
+		}
+
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// Metadata endpoint
+	http.HandleFunc(oauthlib.MetadataPath, func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+		server.HandleMetadataRequest(resp, r)
+		oauthlib.WriteJSON(w, resp)
+	})
+
+	// JWKS endpoint
+	http.HandleFunc(oauthlib.JWKSPath, func(w http.ResponseWriter, r *http.Request) {
+		resp := server.NewResponse()
+		server.HandleJWKSRequest(resp, r)
+		oauthlib.WriteJSON(w, resp)
+	})
+
 	// Application home endpoint
 	http.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("<html><body>"))
@@ -0,0 +1,251 @@
+package oauthlib
+
+import (
+	"crypto"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ScopeOpenID is the scope of OpenID Connect authentication requests, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#AuthRequest
+const ScopeOpenID = "openid"
+
+// responseTypeValues are the response_type values, in the order of the
+// normalized response type.
+var responseTypeValues = []string{"code", "id_token", "token"}
+
+// normalizeResponseType returns the space separated response_type values in
+// a fixed order, so that "token id_token" and "id_token token" compare equal.
+// Returns blank if the response type has unknown or duplicate values.
+//
+// See http://openid.net/specs/oauth-v2-multiple-response-types-1_0.html
+func normalizeResponseType(rt string) string {
+	fields := strings.Fields(rt)
+	var ret []string
+	for _, v := range responseTypeValues {
+		for _, f := range fields {
+			if f == v {
+				ret = append(ret, v)
+				break
+			}
+		}
+	}
+	if len(ret) == 0 || len(ret) != len(fields) {
+		return ""
+	}
+	return strings.Join(ret, " ")
+}
+
+// responseTypeHas determines if the response type has the value v.
+func responseTypeHas(rt, v string) bool {
+	for _, f := range strings.Fields(rt) {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// hasScope determines if name is one of the space or comma separated scopes.
+func hasScope(scope, name string) bool {
+	for _, s := range strings.FieldsFunc(scope, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IDToken is the data of an OpenID Connect ID token, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#IDToken
+type IDToken struct {
+	// Client is the client the token is issued to ("aud").
+	Client Client
+
+	// Subject is the authenticated end-user ("sub").
+	Subject string
+
+	// Nonce is the nonce of the authentication request. Can be blank
+	Nonce string
+
+	// AuthTime is the time the end-user authenticated. Can be zero
+	AuthTime time.Time
+
+	// SessionID is the id of the end-user's login session ("sid"). Can be
+	// blank
+	SessionID string
+
+	// Code is the authorization code issued with the token, for the "c_hash"
+	// claim. Can be blank
+	Code string
+
+	// AccessToken is the access token issued with the token, for the
+	// "at_hash" claim. Can be blank
+	AccessToken string
+
+	// RefreshToken is the refresh token issued with the token, for the
+	// "urn:openid:params:jwt:claim:rt_hash" claim of CIBA push mode tokens.
+	// Can be blank
+	RefreshToken string
+
+	// AuthReqID is the CIBA authentication request id, for the
+	// "urn:openid:params:jwt:claim:auth_req_id" claim of push mode tokens.
+	// Can be blank
+	AuthReqID string
+
+	// Scope is the granted scope.
+	Scope string
+
+	// Claims are the individually requested claims. Can be nil
+	Claims *ClaimsRequest
+
+	// ExpiresIn is the token expiration in seconds.
+	ExpiresIn int32
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+}
+
+// signingAlg returns the signing algorithm of key.
+func signingAlg(key *JSONWebKey) string {
+	if key.Algorithm != "" {
+		return key.Algorithm
+	}
+	if algs := keyAlgs(key.Key); len(algs) != 0 {
+		return algs[0]
+	}
+	return ""
+}
+
+// tokenHash returns the "at_hash" or "c_hash" of token, the base64url
+// encoded left half of the hash of the token using the hash of the signing
+// algorithm, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#CodeIDToken
+func tokenHash(alg, token string) string {
+	h := algHash(alg)
+	if alg == AlgEdDSA {
+		h = crypto.SHA512
+	}
+	if h == 0 {
+		return ""
+	}
+	d := newHash(h)
+	d.Write([]byte(token))
+	sum := d.Sum(nil)
+	return b64(sum[:len(sum)/2])
+}
+
+// generateIDToken generates the signed ID token for data, using the server
+// signing key.
+func (s *Server) generateIDToken(data *IDToken) (string, error) {
+	if data.Subject == "" {
+		return "", errors.New("id token subject required")
+	}
+	key, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+	alg := signingAlg(key)
+
+	claims := JWTClaims{
+		"iss": s.Config.Issuer,
+		"sub": data.Subject,
+		"aud": data.Client.GetID(),
+		"exp": data.CreatedAt.Add(time.Duration(data.ExpiresIn) * time.Second).Unix(),
+		"iat": data.CreatedAt.Unix(),
+	}
+	if data.Nonce != "" {
+		claims["nonce"] = data.Nonce
+	}
+	if !data.AuthTime.IsZero() {
+		claims["auth_time"] = data.AuthTime.Unix()
+	}
+	if data.SessionID != "" {
+		claims["sid"] = data.SessionID
+	}
+	if data.AccessToken != "" {
+		claims["at_hash"] = tokenHash(alg, data.AccessToken)
+	}
+	if data.Code != "" {
+		claims["c_hash"] = tokenHash(alg, data.Code)
+	}
+
+	// see http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.10.3.1
+	if data.AuthReqID != "" {
+		claims["urn:openid:params:jwt:claim:auth_req_id"] = data.AuthReqID
+	}
+	if data.RefreshToken != "" {
+		claims["urn:openid:params:jwt:claim:rt_hash"] = tokenHash(alg, data.RefreshToken)
+	}
+
+	// add requested claims; without an access token, the claims of the scope
+	// are returned in the id token, see:
+	// http://openid.net/specs/openid-connect-core-1_0.html#ScopeClaims
+	var requested map[string]*ClaimRequest
+	if data.Claims != nil {
+		requested = data.Claims.IDToken
+	}
+	scope := ""
+	if data.AccessToken == "" && data.Code == "" {
+		scope = data.Scope
+	}
+	if names := claimNames(scope, requested); len(names) != 0 {
+		userClaims, err := s.getClaims(data.Subject, names)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range userClaims {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	return signClaims(key, claims)
+}
+
+// signClaims signs the claims using key, identified by its key id.
+func signClaims(key *JSONWebKey, claims JWTClaims) (string, error) {
+	var header map[string]interface{}
+	if key.KeyID != "" {
+		header = map[string]interface{}{"kid": key.KeyID}
+	}
+	return signJWT(key.Key, signingAlg(key), header, claims)
+}
+
+// signingAlg returns the signing algorithm of the tokens issued by the
+// server, without rotating the keys of the server KeySet. Blank if the server
+// has no signing key.
+func (s *Server) signingAlg() string {
+	if s.KeySet != nil {
+		return s.KeySet.Algorithm()
+	}
+	if s.SigningKey == nil {
+		return ""
+	}
+	return signingAlg(s.SigningKey)
+}
+
+// verifyIDTokenHint verifies that the id_token_hint is an ID token issued by
+// the server, returning its claims. Expired tokens are accepted.
+func (s *Server) verifyIDTokenHint(idTokenHint string) (JWTClaims, error) {
+	t, err := parseJWT(idTokenHint)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.publicKeys()
+	if err != nil {
+		return nil, err
+	}
+	if err = t.verifyWithKeySet(keys); err != nil {
+		return nil, err
+	}
+	if t.Claims.String("iss") != s.Config.Issuer {
+		return nil, errors.New("id token hint not issued by the server")
+	}
+	if t.Claims.String("sub") == "" {
+		return nil, errors.New("id token hint has no subject")
+	}
+	return t.Claims, nil
+}
@@ -0,0 +1,246 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNormalizeResponseType(t *testing.T) {
+	var tests = []struct {
+		rt  string
+		exp string
+	}{
+		{"code", "code"},
+		{"token id_token", "id_token token"},
+		{"id_token  code", "code id_token"},
+		{"token code id_token", "code id_token token"},
+		{"code code", ""},
+		{"code other", ""},
+		{"", ""},
+	}
+
+	for i, tt := range tests {
+		if d := normalizeResponseType(tt.rt); d != tt.exp {
+			t.Errorf("Unexpected response type (%d): %q", i, d)
+		}
+	}
+}
+
+// verifyIDToken verifies the id token, returning its claims.
+func verifyIDToken(t *testing.T, key *ecdsa.PrivateKey, token interface{}) JWTClaims {
+	s, _ := token.(string)
+	jt, err := parseJWT(s)
+	if err != nil {
+		t.Fatalf("Invalid id token: %v", err)
+	}
+	if err = jt.verify(&key.PublicKey); err != nil {
+		t.Fatalf("Invalid id token signature: %v", err)
+	}
+	if jt.kid() != "k1" {
+		t.Fatalf("Unexpected id token kid: %s", jt.kid())
+	}
+	return jt.Claims
+}
+
+func TestIDTokenAuthorizationCode(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant)
+	server.SigningKey = &JSONWebKey{Key: key, KeyID: "k1"}
+
+	// authorize
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("response_type", "code")
+	req.Form.Set("client_id", "1234")
+	req.Form.Set("scope", "openid profile")
+	req.Form.Set("nonce", "n-0S6_WzA2Mj")
+
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		ar.Subject = "user"
+		server.FinishAuthRequest(resp, req, ar)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if _, ok := resp.Output["id_token"]; ok {
+		t.Fatalf("Id token should not be returned from the authorization endpoint")
+	}
+
+	// exchange code
+	resp = doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {"1"},
+	})
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	claims := verifyIDToken(t, key, resp.Output["id_token"])
+	if claims.String("iss") != "http://localhost:14000" || claims.String("sub") != "user" || claims.String("aud") != "1234" {
+		t.Fatalf("Unexpected id token claims: %v", claims)
+	}
+
+	if claims.String("nonce") != "n-0S6_WzA2Mj" {
+		t.Fatalf("Unexpected nonce: %v", claims["nonce"])
+	}
+
+	if _, ok := claims.Time("auth_time"); !ok {
+		t.Fatalf("Id token should have auth_time")
+	}
+
+	if claims.String("at_hash") != tokenHash(AlgES256, "1") {
+		t.Fatalf("Unexpected at_hash: %v", claims["at_hash"])
+	}
+}
+
+func TestIDTokenNoSigningKey(t *testing.T) {
+	server := newTestServer(t, AuthorizationCodeGrant)
+	storage := server.Storage.(*MemStorage)
+	err := storage.SaveAuthorizeData(&AuthorizeData{
+		Client:      storage.Clients["1234"],
+		Code:        "openid",
+		ExpiresIn:   3600,
+		Scope:       "openid profile",
+		Subject:     "user",
+		CreatedAt:   time.Now(),
+		RedirectURI: "http://localhost:14000/appauth",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// openid scope is a plain scope for oauth servers
+	resp := doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {"openid"},
+	})
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if _, ok := resp.Output["id_token"]; ok {
+		t.Fatalf("Id token should not be issued without a signing key")
+	}
+}
+
+func TestIDTokenHybrid(t *testing.T) {
+	var tests = []struct {
+		rt     string
+		code   bool
+		access bool
+	}{
+		{"id_token", false, false},
+		{"id_token token", false, true},
+		{"code id_token", true, false},
+		{"code id_token token", true, true},
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		server := newTestServer(t)
+		server.Config.AllowedAuthRequestTypes = []string{tt.rt}
+		server.SigningKey = &JSONWebKey{Key: key, KeyID: "k1"}
+
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		req.Form.Set("response_type", tt.rt)
+		req.Form.Set("client_id", "1234")
+		req.Form.Set("scope", "openid")
+		req.Form.Set("state", "a")
+		req.Form.Set("nonce", "n")
+
+		if ar := server.HandleAuthRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			ar.Subject = "user"
+			server.FinishAuthRequest(resp, req, ar)
+		}
+
+		if resp.IsError {
+			t.Fatalf("Should not be an error (%s): %v", tt.rt, resp.InternalError)
+		}
+
+		if !resp.RedirectInFragment || resp.Output["state"] != "a" {
+			t.Fatalf("Response should be a redirect with fragment (%s)", tt.rt)
+		}
+
+		claims := verifyIDToken(t, key, resp.Output["id_token"])
+		if claims.String("nonce") != "n" {
+			t.Fatalf("Unexpected nonce (%s): %v", tt.rt, claims["nonce"])
+		}
+
+		_, hasCode := resp.Output["code"]
+		if hasCode != tt.code || (claims.String("c_hash") != "") != tt.code {
+			t.Fatalf("Unexpected code (%s): %v", tt.rt, resp.Output)
+		}
+		if tt.code && claims.String("c_hash") != tokenHash(AlgES256, "1") {
+			t.Fatalf("Unexpected c_hash (%s): %v", tt.rt, claims["c_hash"])
+		}
+
+		_, hasAccess := resp.Output["access_token"]
+		if hasAccess != tt.access || (claims.String("at_hash") != "") != tt.access {
+			t.Fatalf("Unexpected access token (%s): %v", tt.rt, resp.Output)
+		}
+	}
+}
+
+func TestIDTokenInvalidRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t)
+	server.Config.AllowedAuthRequestTypes = []string{"code id_token"}
+	server.SigningKey = &JSONWebKey{Key: key}
+
+	var tests = []struct {
+		params url.Values
+		err    string
+	}{
+		{url.Values{"response_type": {"code id_token"}, "scope": {"openid"}}, ErrInvalidRequest.Type},
+		{url.Values{"response_type": {"code id_token"}, "nonce": {"n"}}, ErrInvalidRequest.Type},
+		{url.Values{"response_type": {"id_token"}, "scope": {"openid"}, "nonce": {"n"}}, ErrUnsupportedResponseType.Type},
+		{url.Values{"response_type": {"id_token code"}, "scope": {"openid"}, "nonce": {"n"}}, ""},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = tt.params
+		req.Form.Set("client_id", "1234")
+
+		ar := server.HandleAuthRequest(resp, req)
+		if tt.err == "" && ar == nil {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+		} else if tt.err != "" && (ar != nil || resp.ErrorType != tt.err) {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+	}
+}
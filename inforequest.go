@@ -1,7 +1,10 @@
 package oauthlib
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,8 @@ type InfoRequest struct {
 
 // HandleInfoRequest is an http.HandlerFunc for server information
 // NOT an RFC specification.
+//
+// Deprecated: use HandleIntrospectionRequest instead.
 func (s *Server) HandleInfoRequest(w *Response, r *http.Request) *InfoRequest {
 	err := r.ParseForm()
 	if err != nil {
@@ -21,47 +26,87 @@ func (s *Server) HandleInfoRequest(w *Response, r *http.Request) *InfoRequest {
 		return nil
 	}
 
-	bearer := s.checkBearerAuth(r)
-	if bearer == nil {
-		w.SetError(ErrInvalidRequest)
+	// generate info request
+	ret := &InfoRequest{}
+	if ret.Code, ret.AccessGrant = s.loadBearerGrant(w, r); ret.AccessGrant == nil {
 		return nil
 	}
 
-	// generate info request
-	ret := &InfoRequest{
-		Code: bearer.Code,
-	}
+	return ret
+}
 
-	if ret.Code == "" {
+// loadBearerGrant loads the AccessGrant of the bearer token sent in the
+// request, returning the token and the AccessGrant. Tokens bound to a DPoP
+// key must be sent with the DPoP authentication scheme and a proof of that
+// key. Sets an error on the response if the token is invalid.
+func (s *Server) loadBearerGrant(w *Response, r *http.Request) (string, *AccessGrant) {
+	var code string
+	dpop := false
+	if ss := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(ss) == 2 && strings.EqualFold(ss[0], DPoPTokenType) {
+		code, dpop = ss[1], true
+	} else if bearer := s.checkBearerAuth(r); bearer != nil {
+		code = bearer.Code
+	}
+	if code == "" {
 		w.SetError(ErrInvalidRequest)
-		return nil
+		return "", nil
 	}
 
 	// load access data
-	ret.AccessGrant, err = w.Storage.LoadAccessGrant(ret.Code)
+	ag, err := w.Storage.LoadAccessGrant(code)
 	if err != nil {
 		w.SetError(ErrInvalidRequest)
 		w.InternalError = err
-		return nil
+		return "", nil
 	}
-	if ret.AccessGrant == nil {
+	if ag == nil {
 		w.SetError(ErrInvalidRequest)
-		return nil
+		return "", nil
 	}
-	if ret.AccessGrant.Client == nil {
+	if ag.Client == nil {
 		w.SetError(ErrUnauthorizedClient)
-		return nil
+		return "", nil
 	}
-	if ret.AccessGrant.Client.GetRedirectURI() == "" {
+	if ag.Client.GetRedirectURI() == "" {
 		w.SetError(ErrUnauthorizedClient)
-		return nil
+		return "", nil
 	}
-	if ret.AccessGrant.IsExpiredAt(s.Now()) {
+	if ag.IsExpiredAt(s.Now()) {
 		w.SetError(ErrInvalidGrant)
-		return nil
+		return "", nil
+	}
+	if err = checkCertificateBinding(ag, r); err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return "", nil
 	}
 
-	return ret
+	// must be sent with a proof of the bound dpop key, see:
+	// http://tools.ietf.org/html/rfc9449#section-7
+	if ag.DPoPThumbprint != "" || dpop {
+		if ag.DPoPThumbprint == "" || !dpop {
+			w.SetError(ErrInvalidGrant)
+			w.InternalError = errors.New("dpop scheme must be used with dpop bound tokens only")
+			return "", nil
+		}
+
+		jkt, e, err := s.verifyDPoPProof(w.Storage, r, requestURL(r), code)
+		if e != nil {
+			w.SetError(e)
+			w.InternalError = err
+			if e == ErrUseDPoPNonce {
+				s.setDPoPNonce(w)
+			}
+			return "", nil
+		}
+		if subtle.ConstantTimeCompare([]byte(jkt), []byte(ag.DPoPThumbprint)) != 1 {
+			w.SetError(ErrInvalidGrant)
+			w.InternalError = errors.New("dpop proof key does not match token binding")
+			return "", nil
+		}
+	}
+
+	return code, ag
 }
 
 // FinishInfoRequest finalizes the request handled by HandleInfoRequest
@@ -74,7 +119,7 @@ func (s *Server) FinishInfoRequest(w *Response, r *http.Request, ir *InfoRequest
 	// output data
 	w.Output["client_id"] = ir.AccessGrant.Client.GetID()
 	w.Output["access_token"] = ir.AccessGrant.AccessToken
-	w.Output["token_type"] = s.Config.TokenType
+	w.Output["token_type"] = s.tokenType(ir.AccessGrant)
 	w.Output["expires_in"] = ir.AccessGrant.CreatedAt.Add(time.Duration(ir.AccessGrant.ExpiresIn)*time.Second).Sub(s.Now()) / time.Second
 	if ir.AccessGrant.RefreshToken != "" {
 		w.Output["refresh_token"] = ir.AccessGrant.RefreshToken
@@ -0,0 +1,169 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+)
+
+// IntrospectionRequest is a request for information about a token by a
+// protected resource, normally sent to "/introspect" on the server.
+//
+// See http://tools.ietf.org/html/rfc7662
+type IntrospectionRequest struct {
+	// Token is the token to introspect.
+	Token string
+
+	// TokenTypeHint is the passed token type hint in the request.
+	TokenTypeHint string
+
+	// Client is the authenticated client (ie, the protected resource) making
+	// the request.
+	Client Client
+
+	// AccessGrant is the AccessGrant associated with Token. Nil if the token
+	// was not found.
+	AccessGrant *AccessGrant
+
+	// Active toggles if the token is active. Change if the token should be
+	// reported as inactive.
+	Active bool
+
+	// Subject is the resource owner that authorized the token. Defaults to
+	// the AccessGrant subject. Change if a different "sub" field should be
+	// returned.
+	Subject string
+
+	// Audience is the intended audience of the token. Defaults to the
+	// AccessGrant audience. Change if a different "aud" field should be
+	// returned.
+	Audience []string
+
+	// AuthorizationDetails are the authorization details of the token.
+	// Defaults to the AccessGrant authorization details. Change if different
+	// "authorization_details" should be returned.
+	AuthorizationDetails []AuthorizationDetail
+}
+
+// HandleIntrospectionRequest is the http.HandlerFunc for handling token
+// introspection requests.
+//
+// Tokens bound to a client certificate or DPoP key are returned with their
+// "cnf" confirmation. The protected resource must reject the token if it was
+// not presented over that certificate or with a proof of that key (see
+// HandleResourceRequest).
+func (s *Server) HandleIntrospectionRequest(w *Response, r *http.Request) *IntrospectionRequest {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	ret := &IntrospectionRequest{
+		Token:         r.Form.Get("token"),
+		TokenTypeHint: r.Form.Get("token_type_hint"),
+	}
+
+	// "token" is required
+	if ret.Token == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients can't authenticate, so can't be trusted with the token
+	// information
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("public clients cannot introspect tokens")
+		return nil
+	}
+
+	// unknown and expired tokens are inactive, see:
+	// http://tools.ietf.org/html/rfc7662#section-2.2
+	ret.AccessGrant = loadGrantByHint(w.Storage, ret.Token, ret.TokenTypeHint)
+	ret.Active = ret.AccessGrant != nil && ret.AccessGrant.Client != nil
+	if ret.Active && ret.isRefreshToken() {
+		// refresh tokens outlive their access token
+		ret.Active = !s.isRefreshExpiredAt(ret.AccessGrant, s.Now())
+	} else if ret.Active {
+		ret.Active = !ret.AccessGrant.IsExpiredAt(s.Now())
+	}
+	if ret.Active {
+		ret.Subject = ret.AccessGrant.Subject
+		ret.Audience = ret.AccessGrant.Audience
+		ret.AuthorizationDetails = ret.AccessGrant.AuthorizationDetails
+	}
+
+	return ret
+}
+
+// FinishIntrospectionRequest finalizes the request handled by
+// HandleIntrospectionRequest.
+func (s *Server) FinishIntrospectionRequest(w *Response, r *http.Request, ir *IntrospectionRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	// inactive tokens reveal nothing else
+	if !ir.Active || ir.AccessGrant == nil {
+		w.Output["active"] = false
+		return
+	}
+
+	// output data
+	w.Output["active"] = true
+	w.Output["client_id"] = ir.AccessGrant.Client.GetID()
+	w.Output["token_type"] = s.tokenType(ir.AccessGrant)
+	if !ir.isRefreshToken() {
+		w.Output["exp"] = ir.AccessGrant.ExpireAt().Unix()
+	} else if exp, ok := s.refreshExpireAt(ir.AccessGrant); ok {
+		w.Output["exp"] = exp.Unix()
+	}
+	w.Output["iat"] = ir.AccessGrant.CreatedAt.Unix()
+	if ir.AccessGrant.Scope != "" {
+		w.Output["scope"] = ir.AccessGrant.Scope
+	}
+	if ir.Subject != "" {
+		w.Output["sub"] = ir.Subject
+	}
+	cnf := map[string]interface{}{}
+	if ir.AccessGrant.CertificateThumbprint != "" {
+		cnf["x5t#S256"] = ir.AccessGrant.CertificateThumbprint
+	}
+	if ir.AccessGrant.DPoPThumbprint != "" {
+		cnf["jkt"] = ir.AccessGrant.DPoPThumbprint
+	}
+	if len(cnf) != 0 {
+		w.Output["cnf"] = cnf
+	}
+	if ir.AccessGrant.Actor != nil {
+		w.Output["act"] = ir.AccessGrant.Actor
+	}
+	if len(ir.Audience) == 1 {
+		w.Output["aud"] = ir.Audience[0]
+	} else if len(ir.Audience) > 1 {
+		w.Output["aud"] = ir.Audience
+	}
+	if len(ir.AuthorizationDetails) != 0 {
+		w.Output["authorization_details"] = ir.AuthorizationDetails
+	}
+}
+
+// isRefreshToken returns true when the introspected token is the refresh
+// token of the AccessGrant.
+func (ir *IntrospectionRequest) isRefreshToken() bool {
+	return ir.AccessGrant != nil && ir.AccessGrant.RefreshToken != "" &&
+		ir.AccessGrant.RefreshToken == ir.Token
+}
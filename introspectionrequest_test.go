@@ -0,0 +1,202 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIntrospection(t *testing.T) {
+	sconfig := NewConfig()
+	server := NewServer(sconfig, NewTestStorage(t))
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/introspect", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("token", "9999")
+
+	if ir := server.HandleIntrospectionRequest(resp, req); ir != nil {
+		ir.Subject = "test"
+		server.FinishIntrospectionRequest(resp, req, ir)
+	}
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	if d := resp.Output["active"]; d != true {
+		t.Fatalf("Token should be active")
+	}
+
+	if d := resp.Output["client_id"]; d != "1234" {
+		t.Fatalf("Unexpected client id: %s", d)
+	}
+
+	if d := resp.Output["sub"]; d != "test" {
+		t.Fatalf("Unexpected subject: %s", d)
+	}
+
+	if _, ok := resp.Output["refresh_token"]; ok {
+		t.Fatalf("Refresh token should not be returned")
+	}
+}
+
+func TestIntrospectionInactive(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      storage.Clients["1234"],
+		AccessToken: "expired",
+		ExpiresIn:   60,
+		CreatedAt:   time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	for _, token := range []string{"unknown", "expired"} {
+		resp := server.NewResponse()
+
+		req, err := http.NewRequest("POST", "http://localhost:14000/introspect", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("token", token)
+
+		if ir := server.HandleIntrospectionRequest(resp, req); ir != nil {
+			server.FinishIntrospectionRequest(resp, req, ir)
+		}
+
+		if resp.IsError {
+			t.Fatalf("Introspecting %s token should not be an error", token)
+		}
+
+		if d := resp.Output["active"]; d != false {
+			t.Fatalf("Token %s should not be active", token)
+		}
+
+		if len(resp.Output) != 1 {
+			t.Fatalf("Inactive token %s should only return active: %v", token, resp.Output)
+		}
+	}
+}
+
+func TestIntrospectionRefreshToken(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.PublicRefreshExpiration = 3600
+	storage := NewTestStorage(t)
+	storage.Clients["public"] = &DefaultClient{ID: "public", RedirectURI: "http://localhost:14000/appauth"}
+	grants := []*AccessGrant{{
+		Client:       storage.Clients["1234"],
+		AccessToken:  "confidential",
+		RefreshToken: "rconfidential",
+		ExpiresIn:    60,
+		CreatedAt:    time.Now().Add(-2 * time.Hour),
+	}, {
+		Client:       storage.Clients["public"],
+		AccessToken:  "public",
+		RefreshToken: "rpublic",
+		ExpiresIn:    60,
+		CreatedAt:    time.Now().Add(-30 * time.Minute),
+	}, {
+		Client:       storage.Clients["public"],
+		AccessToken:  "expired",
+		RefreshToken: "rexpired",
+		ExpiresIn:    60,
+		CreatedAt:    time.Now().Add(-2 * time.Hour),
+	}}
+	for _, ag := range grants {
+		if err := storage.SaveAccessGrant(ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		token  string
+		active bool
+		exp    bool
+	}{
+		{"rconfidential", true, false},
+		{"rpublic", true, true},
+		{"rexpired", false, false},
+		{"public", false, false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+
+		req, err := http.NewRequest("POST", "http://localhost:14000/introspect", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("token", tt.token)
+		req.Form.Set("token_type_hint", RefreshTokenHint)
+
+		if ir := server.HandleIntrospectionRequest(resp, req); ir != nil {
+			server.FinishIntrospectionRequest(resp, req, ir)
+		}
+
+		if resp.IsError {
+			t.Fatalf("Introspecting %s token should not be an error", tt.token)
+		}
+
+		if d := resp.Output["active"]; d != tt.active {
+			t.Errorf("Unexpected active for %s token (%d): %v", tt.token, i, d)
+		}
+
+		if _, ok := resp.Output["exp"]; ok != tt.exp {
+			t.Errorf("Unexpected exp for %s token (%d): %v", tt.token, i, resp.Output["exp"])
+		}
+	}
+}
+
+func TestIntrospectionPublicClient(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	storage.Clients["public"] = &DefaultClient{ID: "public", RedirectURI: "http://localhost:14000/appauth"}
+	server := NewServer(sconfig, storage)
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/introspect", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("client_id", "public")
+	req.Form.Set("token", "9999")
+
+	if ir := server.HandleIntrospectionRequest(resp, req); ir != nil {
+		t.Fatalf("Public client should not be able to introspect tokens")
+	}
+
+	if resp.ErrorType != ErrInvalidClient.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrInvalidClient.Type, resp.ErrorType)
+	}
+
+	if _, ok := resp.Output["sub"]; ok {
+		t.Fatalf("Token information should not be returned")
+	}
+}
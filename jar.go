@@ -0,0 +1,238 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// maxRequestObjectSize is the maximum size of a request object loaded by
+// reference.
+const maxRequestObjectSize = 64 * 1024
+
+// RequestObjectLoader loads the request objects of authorization requests
+// passed by reference, see:
+// http://tools.ietf.org/html/rfc9101#section-5.2
+type RequestObjectLoader interface {
+	// LoadRequestObject returns the request object at requestURI, sent by
+	// client.
+	LoadRequestObject(client Client, requestURI string) (string, error)
+}
+
+// RequestObjectLoaderDefault is the default request object loader. Request
+// objects are fetched using HTTPS, from request uris registered in the client
+// metadata only.
+type RequestObjectLoaderDefault struct {
+	// Client is the HTTP client used to fetch request objects. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// LoadRequestObject fetches the request object at requestURI.
+func (a *RequestObjectLoaderDefault) LoadRequestObject(client Client, requestURI string) (string, error) {
+	// must be registered
+	c, ok := client.(ClientMetadataGetter)
+	if !ok || c.GetMetadata() == nil {
+		return "", errors.New("client has no registered request uris")
+	}
+	registered := false
+	for _, uri := range c.GetMetadata().RequestURIs {
+		registered = registered || uri == requestURI
+	}
+	if !registered {
+		return "", errors.New("request uri not registered: " + requestURI)
+	}
+
+	u, err := url.Parse(requestURI)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "https" {
+		return "", errors.New("request uri must use https")
+	}
+
+	hc := a.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request uri returned status %d", res.StatusCode)
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(res.Body, maxRequestObjectSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(buf) > maxRequestObjectSize {
+		return "", errors.New("request object too large")
+	}
+
+	return string(buf), nil
+}
+
+// isSignedRequestObjectRequired determines if the client must send its
+// authorization requests as signed request objects.
+func (s *Server) isSignedRequestObjectRequired(client Client) bool {
+	if s.Config.RequireSignedRequestObject {
+		return true
+	}
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil {
+			return md.RequireSignedRequestObject
+		}
+	}
+	return false
+}
+
+// requestObjectParams returns the authorization request parameters of the
+// request object claims. Non-string claim values are JSON encoded.
+func requestObjectParams(claims JWTClaims) (url.Values, error) {
+	params := url.Values{}
+	for k, v := range claims {
+		switch k {
+		case "iss", "aud", "exp", "iat", "nbf", "jti":
+			continue
+		}
+
+		if str, ok := v.(string); ok {
+			params.Set(k, str)
+			continue
+		}
+
+		// multiple resource indicators are passed as an array, see:
+		// http://tools.ietf.org/html/rfc8707#section-2
+		if a, ok := v.([]interface{}); ok && k == "resource" {
+			for _, e := range a {
+				str, ok := e.(string)
+				if !ok {
+					return nil, errors.New("invalid resource")
+				}
+				params.Add(k, str)
+			}
+			continue
+		}
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		params.Set(k, string(buf))
+	}
+	return params, nil
+}
+
+// resolveRequestObject verifies the signed request object of the
+// authorization request, returning the parameters and claims of the request
+// object. The request object must be addressed to the server and expire, and
+// can only be used once when it has a "jti". Sets an error on the response if
+// the request object is invalid.
+//
+// See http://tools.ietf.org/html/rfc9101#section-6
+func (s *Server) resolveRequestObject(w *Response, params url.Values) (url.Values, JWTClaims) {
+	id := params.Get("client_id")
+	if params.Get("request") != "" && params.Get("request_uri") != "" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request and request_uri must not both be sent")
+		return nil, nil
+	}
+
+	// must have a valid client
+	client, err := w.Storage.GetClient(id)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil, nil
+	}
+	if client == nil {
+		w.SetError(ErrUnauthorizedClient)
+		return nil, nil
+	}
+
+	// load request object by reference
+	request := params.Get("request")
+	if uri := params.Get("request_uri"); uri != "" {
+		if s.RequestObjectLoader == nil {
+			w.SetError(ErrRequestURINotSupported)
+			return nil, nil
+		}
+		if request, err = s.RequestObjectLoader.LoadRequestObject(client, uri); err != nil {
+			w.SetError(ErrInvalidRequestURI)
+			w.InternalError = err
+			return nil, nil
+		}
+	}
+
+	// verify signature with the client keys
+	t, err := parseJWT(request)
+	if err != nil {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = err
+		return nil, nil
+	}
+	if err = t.verifyWithKeySet(clientAssertionKeys(client, t.alg())); err != nil {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = err
+		return nil, nil
+	}
+
+	// check claims
+	if t.Claims.String("client_id") != id {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = errors.New("request object client_id does not match")
+		return nil, nil
+	}
+	if iss, ok := t.Claims["iss"]; ok && iss != id {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = errors.New("request object iss must be the client id")
+		return nil, nil
+	}
+	if !t.Claims.HasAudience(s.Config.Issuer) {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = errors.New("request object aud does not identify the server")
+		return nil, nil
+	}
+	if err = t.Claims.validateTimes(s.Now()); err != nil {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = err
+		return nil, nil
+	}
+
+	// request objects with a "jti" can only be used once
+	if jti := t.Claims.String("jti"); jti != "" {
+		exp, _ := t.Claims.Time("exp")
+		if err = saveJTI(w.Storage, id+" "+jti, exp.Add(jwtLeeway)); err != nil {
+			w.SetError(ErrInvalidRequestObject)
+			w.InternalError = err
+			return nil, nil
+		}
+	}
+
+	ret, err := requestObjectParams(t.Claims)
+	if err != nil {
+		w.SetError(ErrInvalidRequestObject)
+		w.InternalError = err
+		return nil, nil
+	}
+
+	// plain parameters must not conflict with the request object
+	for k := range params {
+		switch k {
+		case "client_id", "request", "request_uri":
+			continue
+		}
+		if v, ok := ret[k]; ok && (len(v) != 1 || v[0] != params.Get(k)) {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = errors.New("parameter conflicts with request object: " + k)
+			return nil, nil
+		}
+	}
+
+	return ret, t.Claims
+}
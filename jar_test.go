@@ -0,0 +1,176 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAuthRequestObject(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.Issuer = "http://localhost:14000"
+	storage := NewTestStorage(t)
+	err = storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			JWKS:                       &JSONWebKeySet{Keys: []JSONWebKey{{Key: &key.PublicKey}}},
+			RequireSignedRequestObject: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	sign := func(key interface{}, alg string, c JWTClaims) string {
+		request, err := signJWT(key, alg, nil, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return request
+	}
+	claims := JWTClaims{
+		"iss":           "5678",
+		"aud":           "http://localhost:14000",
+		"exp":           time.Now().Add(time.Minute).Unix(),
+		"client_id":     "5678",
+		"response_type": "code",
+		"scope":         "read",
+		"state":         "a",
+	}
+	expired := JWTClaims{}
+	for k, v := range claims {
+		expired[k] = v
+	}
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	unbounded, unaddressed, once := JWTClaims{}, JWTClaims{}, JWTClaims{"jti": "r1"}
+	for k, v := range claims {
+		if k != "exp" {
+			unbounded[k] = v
+		}
+		if k != "aud" {
+			unaddressed[k] = v
+		}
+		once[k] = v
+	}
+
+	var tests = []struct {
+		params url.Values
+		err    string
+	}{
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, claims)}}, ""},
+		{url.Values{"client_id": {"5678"}, "response_type": {"code"}, "state": {"a"}, "request": {sign(key, AlgES256, claims)}}, ""},
+		{url.Values{"client_id": {"5678"}, "response_type": {"code"}, "state": {"b"}, "request": {sign(key, AlgES256, claims)}}, ErrInvalidRequest.Type},
+		{url.Values{"client_id": {"5678"}, "request": {sign(otherKey, AlgES256, claims)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, expired)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, unbounded)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, unaddressed)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, once)}}, ""},
+		{url.Values{"client_id": {"5678"}, "request": {sign(key, AlgES256, once)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"1234"}, "request": {sign(key, AlgES256, claims)}}, ErrInvalidRequestObject.Type},
+		{url.Values{"client_id": {"5678"}, "request_uri": {"https://localhost/request"}}, ErrRequestURINotSupported.Type},
+		{url.Values{"client_id": {"5678"}, "response_type": {"code"}, "state": {"a"}}, ErrInvalidRequest.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = tt.params
+
+		ar := server.HandleAuthRequest(resp, req)
+		if tt.err == "" {
+			if ar == nil {
+				t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+			} else if ar.Scope != "read" || ar.State != "a" || ar.RequestObject == nil {
+				t.Errorf("Unexpected authorization request (%d): %+v", i, ar)
+			}
+		} else if ar != nil || resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestAuthRequestObjectByReference(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := signJWT(key, AlgES256, nil, JWTClaims{
+		"iss":           "5678",
+		"aud":           "http://localhost:14000",
+		"exp":           time.Now().Add(time.Minute).Unix(),
+		"client_id":     "5678",
+		"response_type": "code",
+		"scope":         "read",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/oauth-authz-req+jwt")
+		w.Write([]byte(request))
+	}))
+	defer ts.Close()
+
+	sconfig := NewConfig()
+	sconfig.Issuer = "http://localhost:14000"
+	storage := NewTestStorage(t)
+	err = storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			JWKS:        &JSONWebKeySet{Keys: []JSONWebKey{{Key: &key.PublicKey}}},
+			RequestURIs: []string{ts.URL + "/request"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	server.RequestObjectLoader = &RequestObjectLoaderDefault{Client: ts.Client()}
+
+	var tests = []struct {
+		uri   string
+		valid bool
+	}{
+		{ts.URL + "/request", true},
+		{ts.URL + "/unregistered", false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		req.Form.Set("client_id", "5678")
+		req.Form.Set("request_uri", tt.uri)
+
+		ar := server.HandleAuthRequest(resp, req)
+		if tt.valid && (ar == nil || ar.Scope != "read") {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+		} else if !tt.valid && (ar != nil || resp.ErrorType != ErrInvalidRequestURI.Type) {
+			t.Errorf("Expected invalid request uri error (%d), got: %q", i, resp.ErrorType)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// JSONWebKey is a JSON Web Key, see:
+// http://tools.ietf.org/html/rfc7517
+type JSONWebKey struct {
+	// Key is the key, one of *rsa.PublicKey, *rsa.PrivateKey,
+	// *ecdsa.PublicKey, *ecdsa.PrivateKey, ed25519.PublicKey,
+	// ed25519.PrivateKey, or []byte for symmetric keys.
+	Key interface{}
+
+	// KeyID is the key id ("kid").
+	KeyID string
+
+	// Algorithm is the intended signing algorithm ("alg").
+	Algorithm string
+
+	// Use is the intended use ("use").
+	Use string
+}
+
+// jsonWebKey is the json representation of a JSONWebKey.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	K   string `json:"k,omitempty"`
+}
+
+// publicKey returns the public key of key. Symmetric keys are returned as is.
+func publicKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	}
+	return key
+}
+
+// b64 encodes buf using unpadded base64 url encoding.
+func b64(buf []byte) string {
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// toJSON builds the json representation of the public part of the key.
+func (k JSONWebKey) toJSON() (*jsonWebKey, error) {
+	ret := &jsonWebKey{
+		Kid: k.KeyID,
+		Alg: k.Algorithm,
+		Use: k.Use,
+	}
+
+	switch key := publicKey(k.Key).(type) {
+	case *rsa.PublicKey:
+		ret.Kty = "RSA"
+		ret.N = b64(key.N.Bytes())
+		ret.E = b64(big.NewInt(int64(key.E)).Bytes())
+
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		x, y := make([]byte, size), make([]byte, size)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		ret.Kty = "EC"
+		ret.Crv = key.Curve.Params().Name
+		ret.X = b64(x)
+		ret.Y = b64(y)
+
+	case ed25519.PublicKey:
+		ret.Kty = "OKP"
+		ret.Crv = "Ed25519"
+		ret.X = b64(key)
+
+	case []byte:
+		ret.Kty = "oct"
+		ret.K = b64(key)
+
+	default:
+		return nil, errors.New("unsupported json web key")
+	}
+
+	return ret, nil
+}
+
+// MarshalJSON satisfies the json.Marshaler interface. Only the public part of
+// asymmetric keys is marshaled.
+func (k JSONWebKey) MarshalJSON() ([]byte, error) {
+	jwk, err := k.toJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwk)
+}
+
+// Thumbprint returns the base64url encoded SHA-256 thumbprint of the public
+// part of the key, see:
+// http://tools.ietf.org/html/rfc7638
+func (k JSONWebKey) Thumbprint() (string, error) {
+	jwk, err := k.toJSON()
+	if err != nil {
+		return "", err
+	}
+
+	// only the required members, in lexicographic order
+	var v interface{}
+	switch jwk.Kty {
+	case "RSA":
+		v = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		v = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	case "OKP":
+		v = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		v = struct {
+			K   string `json:"k"`
+			Kty string `json:"kty"`
+		}{jwk.K, jwk.Kty}
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(buf)
+	return b64(hash[:]), nil
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface. Private key
+// parameters are ignored.
+func (k *JSONWebKey) UnmarshalJSON(buf []byte) error {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(buf, &jwk); err != nil {
+		return err
+	}
+
+	decode := func(s string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(b)
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		if jwk.N == "" || jwk.E == "" {
+			return errors.New("invalid rsa json web key")
+		}
+		k.Key = &rsa.PublicKey{N: decode(jwk.N), E: int(decode(jwk.E).Int64())}
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return errors.New("unsupported ec json web key curve: " + jwk.Crv)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: decode(jwk.X), Y: decode(jwk.Y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return errors.New("invalid ec json web key")
+		}
+		k.Key = key
+
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || jwk.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return errors.New("invalid okp json web key")
+		}
+		k.Key = ed25519.PublicKey(x)
+
+	case "oct":
+		key, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return err
+		}
+		k.Key = key
+
+	default:
+		return errors.New("unsupported json web key type: " + jwk.Kty)
+	}
+
+	k.KeyID = jwk.Kid
+	k.Algorithm = jwk.Alg
+	k.Use = jwk.Use
+	return nil
+}
+
+// JSONWebKeySet is a JSON Web Key Set, see:
+// http://tools.ietf.org/html/rfc7517#section-5
+type JSONWebKeySet struct {
+	// Keys are the keys in the set.
+	Keys []JSONWebKey `json:"keys"`
+}
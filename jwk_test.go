@@ -0,0 +1,67 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONWebKeyRoundTrip(t *testing.T) {
+	for alg, key := range newTestKeys(t) {
+		buf, err := json.Marshal(JSONWebKey{Key: key, KeyID: alg})
+		if err != nil {
+			t.Fatalf("Error marshaling %s key: %s", alg, err)
+		}
+
+		var jwk JSONWebKey
+		if err = json.Unmarshal(buf, &jwk); err != nil {
+			t.Fatalf("Error unmarshaling %s key: %s", alg, err)
+		}
+
+		if jwk.KeyID != alg {
+			t.Fatalf("Unexpected %s key id: %s", alg, jwk.KeyID)
+		}
+
+		if !reflect.DeepEqual(jwk.Key, publicKey(key)) {
+			t.Fatalf("Unexpected %s key: %v", alg, jwk.Key)
+		}
+	}
+}
+
+func TestJSONWebKeyMarshalPublicOnly(t *testing.T) {
+	keys := newTestKeys(t)
+
+	buf, err := json.Marshal(JSONWebKey{Key: keys[AlgRS256]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]interface{}
+	if err = json.Unmarshal(buf, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"d", "p", "q", "dp", "dq", "qi"} {
+		if _, ok := m[k]; ok {
+			t.Fatalf("Marshaled key should not contain private parameter %s", k)
+		}
+	}
+}
+
+func TestJSONWebKeyThumbprint(t *testing.T) {
+	// example from http://tools.ietf.org/html/rfc7638#section-3.1
+	buf := []byte(`{"kty":"RSA","n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw","e":"AQAB","alg":"RS256","kid":"2011-04-29"}`)
+
+	var jwk JSONWebKey
+	if err := json.Unmarshal(buf, &jwk); err != nil {
+		t.Fatal(err)
+	}
+
+	thumbprint, err := jwk.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Fatalf("Unexpected thumbprint: %s", thumbprint)
+	}
+}
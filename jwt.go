@@ -0,0 +1,403 @@
+package oauthlib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWT signing algorithms, see:
+// http://tools.ietf.org/html/rfc7518#section-3.1
+// http://tools.ietf.org/html/rfc8037#section-3.1
+const (
+	// AlgHS256 is HMAC using SHA-256.
+	AlgHS256 = "HS256"
+
+	// AlgHS384 is HMAC using SHA-384.
+	AlgHS384 = "HS384"
+
+	// AlgHS512 is HMAC using SHA-512.
+	AlgHS512 = "HS512"
+
+	// AlgRS256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgRS256 = "RS256"
+
+	// AlgRS384 is RSASSA-PKCS1-v1_5 using SHA-384.
+	AlgRS384 = "RS384"
+
+	// AlgRS512 is RSASSA-PKCS1-v1_5 using SHA-512.
+	AlgRS512 = "RS512"
+
+	// AlgPS256 is RSASSA-PSS using SHA-256.
+	AlgPS256 = "PS256"
+
+	// AlgES256 is ECDSA using P-256 and SHA-256.
+	AlgES256 = "ES256"
+
+	// AlgES384 is ECDSA using P-384 and SHA-384.
+	AlgES384 = "ES384"
+
+	// AlgES512 is ECDSA using P-521 and SHA-512.
+	AlgES512 = "ES512"
+
+	// AlgEdDSA is EdDSA using Ed25519.
+	AlgEdDSA = "EdDSA"
+)
+
+// jwtLeeway is the allowed clock skew when validating JWT time claims.
+const jwtLeeway = 30 * time.Second
+
+// algHash returns the hash used by the signing algorithm.
+func algHash(alg string) crypto.Hash {
+	switch alg {
+	case AlgHS256, AlgRS256, AlgPS256, AlgES256:
+		return crypto.SHA256
+	case AlgHS384, AlgRS384, AlgES384:
+		return crypto.SHA384
+	case AlgHS512, AlgRS512, AlgES512:
+		return crypto.SHA512
+	}
+	return 0
+}
+
+// newHash returns a new hash.Hash for h.
+func newHash(h crypto.Hash) hash.Hash {
+	switch h {
+	case crypto.SHA256:
+		return sha256.New()
+	case crypto.SHA384:
+		return sha512.New384()
+	case crypto.SHA512:
+		return sha512.New()
+	}
+	return nil
+}
+
+// digest returns the digest of data using the hash of the signing algorithm.
+func digest(alg string, data []byte) []byte {
+	h := newHash(algHash(alg))
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// keyAlgs returns the signing algorithms that can be used with key.
+func keyAlgs(key interface{}) []string {
+	switch k := key.(type) {
+	case []byte:
+		return []string{AlgHS256, AlgHS384, AlgHS512}
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return []string{AlgRS256, AlgRS384, AlgRS512, AlgPS256}
+	case *ecdsa.PublicKey:
+		return []string{ecdsaAlg(k)}
+	case *ecdsa.PrivateKey:
+		return []string{ecdsaAlg(&k.PublicKey)}
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return []string{AlgEdDSA}
+	}
+	return nil
+}
+
+// ecdsaAlg returns the signing algorithm for the curve of key.
+func ecdsaAlg(key *ecdsa.PublicKey) string {
+	switch key.Curve.Params().BitSize {
+	case 256:
+		return AlgES256
+	case 384:
+		return AlgES384
+	case 521:
+		return AlgES512
+	}
+	return ""
+}
+
+// isKeyAlg determines if alg can be used with key.
+func isKeyAlg(key interface{}, alg string) bool {
+	for _, k := range keyAlgs(key) {
+		if k == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTClaims are the claims of a JWT.
+type JWTClaims map[string]interface{}
+
+// String returns the string claim name, or blank if not present.
+func (c JWTClaims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Time returns the NumericDate claim name, and whether it was present.
+func (c JWTClaims) Time(name string) (time.Time, bool) {
+	switch v := c[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	case json.Number:
+		i, err := v.Int64()
+		return time.Unix(i, 0), err == nil
+	}
+	return time.Time{}, false
+}
+
+// Audience returns the "aud" claim as a list.
+func (c JWTClaims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var ret []string
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+		return ret
+	}
+	return nil
+}
+
+// HasAudience determines if any of aud is in the "aud" claim.
+func (c JWTClaims) HasAudience(aud ...string) bool {
+	for _, a := range c.Audience() {
+		for _, b := range aud {
+			if b != "" && a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateTimes validates the "exp", "nbf" and "iat" claims at time now. The
+// "exp" claim is required.
+func (c JWTClaims) validateTimes(now time.Time) error {
+	exp, ok := c.Time("exp")
+	if !ok {
+		return errors.New("jwt exp required")
+	}
+	if now.After(exp.Add(jwtLeeway)) {
+		return errors.New("jwt expired")
+	}
+	if nbf, ok := c.Time("nbf"); ok && now.Add(jwtLeeway).Before(nbf) {
+		return errors.New("jwt not yet valid")
+	}
+	if iat, ok := c.Time("iat"); ok && now.Add(jwtLeeway).Before(iat) {
+		return errors.New("jwt issued in the future")
+	}
+	return nil
+}
+
+// jwt is a parsed, unverified JWT.
+type jwt struct {
+	Header    map[string]interface{}
+	Claims    JWTClaims
+	signing   string
+	signature []byte
+}
+
+// alg returns the "alg" header.
+func (t *jwt) alg() string {
+	s, _ := t.Header["alg"].(string)
+	return s
+}
+
+// kid returns the "kid" header.
+func (t *jwt) kid() string {
+	s, _ := t.Header["kid"].(string)
+	return s
+}
+
+// typ returns the "typ" header.
+func (t *jwt) typ() string {
+	s, _ := t.Header["typ"].(string)
+	return s
+}
+
+// parseJWT parses a compact serialized JWS, without verifying its signature.
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt must have 3 parts")
+	}
+
+	t := &jwt{
+		signing: parts[0] + "." + parts[1],
+	}
+
+	// decode header
+	buf, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(buf, &t.Header); err != nil {
+		return nil, err
+	}
+
+	// decode claims
+	buf, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(buf, &t.Claims); err != nil {
+		return nil, err
+	}
+	if t.Claims == nil {
+		return nil, errors.New("jwt claims must be an object")
+	}
+
+	// decode signature
+	t.signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// verify verifies the JWT signature using key.
+func (t *jwt) verify(key interface{}) error {
+	alg := t.alg()
+	if !isKeyAlg(key, alg) {
+		return errors.New("jwt alg not supported by key: " + alg)
+	}
+
+	data := []byte(t.signing)
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(func() hash.Hash { return newHash(algHash(alg)) }, k)
+		mac.Write(data)
+		if !hmac.Equal(mac.Sum(nil), t.signature) {
+			return errors.New("invalid jwt signature")
+		}
+		return nil
+
+	case *rsa.PublicKey:
+		if alg == AlgPS256 {
+			return rsa.VerifyPSS(k, crypto.SHA256, digest(alg, data), t.signature, nil)
+		}
+		return rsa.VerifyPKCS1v15(k, algHash(alg), digest(alg, data), t.signature)
+
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return errors.New("invalid jwt signature")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(k, digest(alg, data), r, s) {
+			return errors.New("invalid jwt signature")
+		}
+		return nil
+
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, t.signature) {
+			return errors.New("invalid jwt signature")
+		}
+		return nil
+	}
+
+	return errors.New("unsupported jwt verification key")
+}
+
+// verifyWithKeySet verifies the JWT signature using the keys in the key set
+// matching the "kid" header.
+func (t *jwt) verifyWithKeySet(keys *JSONWebKeySet) error {
+	if keys == nil {
+		return errors.New("no jwt verification keys")
+	}
+
+	err := errors.New("no matching jwt verification key")
+	for _, k := range keys.Keys {
+		if t.kid() != "" && k.KeyID != t.kid() {
+			continue
+		}
+		if k.Algorithm != "" && k.Algorithm != t.alg() {
+			continue
+		}
+		if err = t.verify(publicKey(k.Key)); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// signJWT creates a compact serialized JWS of the claims using key and the
+// signing algorithm alg. Additional header fields can be passed in header.
+func signJWT(key interface{}, alg string, header map[string]interface{}, claims interface{}) (string, error) {
+	if !isKeyAlg(key, alg) {
+		return "", errors.New("jwt alg not supported by key: " + alg)
+	}
+
+	h := map[string]interface{}{}
+	for k, v := range header {
+		h[k] = v
+	}
+	h["alg"] = alg
+
+	hbuf, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	cbuf, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(hbuf) + "." + base64.RawURLEncoding.EncodeToString(cbuf)
+
+	var sig []byte
+	data := []byte(signing)
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(func() hash.Hash { return newHash(algHash(alg)) }, k)
+		mac.Write(data)
+		sig = mac.Sum(nil)
+
+	case *rsa.PrivateKey:
+		if alg == AlgPS256 {
+			sig, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest(alg, data), nil)
+		} else {
+			sig, err = rsa.SignPKCS1v15(rand.Reader, k, algHash(alg), digest(alg, data))
+		}
+
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest(alg, data))
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			sig = make([]byte, 2*size)
+			r.FillBytes(sig[:size])
+			s.FillBytes(sig[size:])
+		}
+
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, data)
+
+	default:
+		err = errors.New("unsupported jwt signing key")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
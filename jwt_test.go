@@ -0,0 +1,101 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+// newTestKeys generates one signing key of each supported type.
+func newTestKeys(t *testing.T) map[string]interface{} {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		AlgHS256: []byte("01234567890123456789012345678901"),
+		AlgRS256: rsaKey,
+		AlgPS256: rsaKey,
+		AlgES256: ecKey,
+		AlgEdDSA: edKey,
+	}
+}
+
+func TestSignVerifyJWT(t *testing.T) {
+	for alg, key := range newTestKeys(t) {
+		token, err := signJWT(key, alg, map[string]interface{}{"kid": "k1"}, JWTClaims{"sub": "test"})
+		if err != nil {
+			t.Fatalf("Error signing %s jwt: %s", alg, err)
+		}
+
+		jt, err := parseJWT(token)
+		if err != nil {
+			t.Fatalf("Error parsing %s jwt: %s", alg, err)
+		}
+
+		if jt.alg() != alg || jt.kid() != "k1" || jt.Claims.String("sub") != "test" {
+			t.Fatalf("Unexpected %s jwt: %+v", alg, jt)
+		}
+
+		if err = jt.verify(publicKey(key)); err != nil {
+			t.Fatalf("Error verifying %s jwt: %s", alg, err)
+		}
+
+		// tampered signature must fail
+		jt.signature[0] ^= 0xff
+		if err = jt.verify(publicKey(key)); err == nil {
+			t.Fatalf("Verifying tampered %s jwt should have failed", alg)
+		}
+	}
+}
+
+func TestVerifyJWTAlgMismatch(t *testing.T) {
+	keys := newTestKeys(t)
+
+	token, err := signJWT(keys[AlgHS256], AlgHS256, nil, JWTClaims{"sub": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jt, err := parseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = jt.verify(publicKey(keys[AlgRS256])); err == nil {
+		t.Fatalf("Verifying HS256 jwt with rsa key should have failed")
+	}
+}
+
+func TestJWTClaimsValidateTimes(t *testing.T) {
+	now := time.Now()
+
+	valid := JWTClaims{"exp": float64(now.Add(time.Minute).Unix()), "nbf": float64(now.Unix())}
+	if err := valid.validateTimes(now); err != nil {
+		t.Errorf("Expected valid claims, got: %s", err)
+	}
+
+	invalid := []JWTClaims{
+		{},
+		{"exp": float64(now.Add(-time.Hour).Unix())},
+		{"exp": float64(now.Add(time.Hour).Unix()), "nbf": float64(now.Add(time.Minute).Unix())},
+	}
+	for i, c := range invalid {
+		if err := c.validateTimes(now); err == nil {
+			t.Errorf("Expected invalid claims (%d): %v", i, c)
+		}
+	}
+}
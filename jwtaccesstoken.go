@@ -0,0 +1,203 @@
+package oauthlib
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// jwtAccessTokenType is the "typ" header of JWT access tokens, see:
+// http://tools.ietf.org/html/rfc9068#section-2.1
+const jwtAccessTokenType = "at+jwt"
+
+// jwtAccessTokenAlgs are the signing algorithms of JWT access tokens.
+var jwtAccessTokenAlgs = []string{AlgRS256, AlgES256, AlgEdDSA}
+
+// isJWTAccessTokenAlg determines if alg can be used to sign JWT access tokens.
+func isJWTAccessTokenAlg(alg string) bool {
+	for _, a := range jwtAccessTokenAlgs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokenGenJWT is an access token generator producing signed JWT access
+// tokens, see:
+// http://tools.ietf.org/html/rfc9068
+//
+// Refresh tokens are base64-encoded UUIDs, as with AccessTokenGenDefault.
+type AccessTokenGenJWT struct {
+	// Key is the signing key, one of *rsa.PrivateKey (RS256),
+	// *ecdsa.PrivateKey using P-256 (ES256) or ed25519.PrivateKey (EdDSA).
+	Key interface{}
+
+	// KeyID is the "kid" header of the tokens. Can be blank
+	KeyID string
+
+	// KeySet manages the rotated signing keys. If set, tokens are signed
+	// with the active key of the KeySet instead of Key.
+	KeySet *KeySet
+
+	// Issuer is the "iss" claim of the tokens, normally Config.Issuer.
+	Issuer string
+
+	// Audience is the "aud" claim of tokens without an AccessGrant audience.
+	Audience []string
+}
+
+// jwtAccessTokenClaims returns the claims of the JWT access token for data.
+func (a *AccessTokenGenJWT) jwtAccessTokenClaims(data *AccessGrant) JWTClaims {
+	claims := JWTClaims{
+		"iss":       a.Issuer,
+		"exp":       data.ExpireAt().Unix(),
+		"iat":       data.CreatedAt.Unix(),
+		"jti":       uuid.New(),
+		"client_id": data.Client.GetID(),
+	}
+
+	// tokens without a resource owner have the client as subject, see:
+	// http://tools.ietf.org/html/rfc9068#section-2.2
+	claims["sub"] = data.Subject
+	if data.Subject == "" {
+		claims["sub"] = data.Client.GetID()
+	}
+
+	aud := data.Audience
+	if len(aud) == 0 {
+		aud = a.Audience
+	}
+	if len(aud) == 1 {
+		claims["aud"] = aud[0]
+	} else if len(aud) > 1 {
+		claims["aud"] = aud
+	}
+
+	if data.Scope != "" {
+		claims["scope"] = data.Scope
+	}
+
+	cnf := map[string]interface{}{}
+	if data.CertificateThumbprint != "" {
+		cnf["x5t#S256"] = data.CertificateThumbprint
+	}
+	if data.DPoPThumbprint != "" {
+		cnf["jkt"] = data.DPoPThumbprint
+	}
+	if len(cnf) != 0 {
+		claims["cnf"] = cnf
+	}
+
+	if data.Actor != nil {
+		claims["act"] = data.Actor
+	}
+
+	// see http://tools.ietf.org/html/rfc9396#section-9.1
+	if len(data.AuthorizationDetails) != 0 {
+		claims["authorization_details"] = data.AuthorizationDetails
+	}
+
+	return claims
+}
+
+// GenerateAccessToken generates a signed JWT access token and a
+// base64-encoded UUID refresh token.
+func (a *AccessTokenGenJWT) GenerateAccessToken(data *AccessGrant, generaterefresh bool) (accesstoken string, refreshtoken string, err error) {
+	key := &JSONWebKey{Key: a.Key, KeyID: a.KeyID}
+	if a.KeySet != nil {
+		if key, err = a.KeySet.ActiveKey(); err != nil {
+			return "", "", err
+		}
+	}
+	alg := signingAlg(key)
+	if !isKeyAlg(key.Key, alg) || !isJWTAccessTokenAlg(alg) {
+		return "", "", errors.New("unsupported jwt access token signing key")
+	}
+
+	header := map[string]interface{}{"typ": jwtAccessTokenType}
+	if key.KeyID != "" {
+		header["kid"] = key.KeyID
+	}
+
+	accesstoken, err = signJWT(key.Key, alg, header, a.jwtAccessTokenClaims(data))
+	if err != nil {
+		return "", "", err
+	}
+
+	if generaterefresh {
+		rtoken := uuid.NewRandom()
+		refreshtoken = removePadding(base64.URLEncoding.EncodeToString([]byte(rtoken)))
+	}
+	return
+}
+
+// JWTAccessTokenValidator validates JWT access tokens at protected resources,
+// without loading the AccessGrant from storage, see:
+// http://tools.ietf.org/html/rfc9068#section-4
+type JWTAccessTokenValidator struct {
+	// Issuer is the expected "iss" claim.
+	Issuer string
+
+	// Audience is the resource identifier that must be in the "aud" claim.
+	Audience string
+
+	// Keys are the public keys of the issuer.
+	Keys *JSONWebKeySet
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+}
+
+// ValidateAccessToken verifies the signature and claims of the JWT access
+// token, returning the claims of a valid token.
+//
+// Tokens bound to a client certificate or DPoP key have the "cnf" claim; the
+// caller must check the binding.
+func (v *JWTAccessTokenValidator) ValidateAccessToken(token string) (JWTClaims, error) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// check header
+	if typ := strings.ToLower(t.typ()); typ != jwtAccessTokenType && typ != "application/"+jwtAccessTokenType {
+		return nil, errors.New("jwt typ must be at+jwt")
+	}
+	if !isJWTAccessTokenAlg(t.alg()) {
+		return nil, errors.New("jwt alg not allowed: " + t.alg())
+	}
+
+	// verify signature
+	if err = t.verifyWithKeySet(v.Keys); err != nil {
+		return nil, err
+	}
+
+	// check claims
+	if t.Claims.String("iss") != v.Issuer {
+		return nil, errors.New("jwt iss does not match")
+	}
+	if !t.Claims.HasAudience(v.Audience) {
+		return nil, errors.New("jwt aud does not identify the resource")
+	}
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	if err = t.Claims.validateTimes(now); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"sub", "client_id", "jti"} {
+		if t.Claims.String(name) == "" {
+			return nil, errors.New("jwt " + name + " required")
+		}
+	}
+	if _, ok := t.Claims.Time("iat"); !ok {
+		return nil, errors.New("jwt iat required")
+	}
+
+	return t.Claims, nil
+}
@@ -0,0 +1,153 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAccessTokenGenJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []interface{}{rsaKey, ecKey, edKey} {
+		sconfig := NewConfig()
+		sconfig.Issuer = "http://localhost:14000"
+		sconfig.AllowedGrantTypes = []GrantType{ClientCredentialsGrant}
+		server := NewServer(sconfig, NewTestStorage(t))
+		server.AccessTokenGen = &AccessTokenGenJWT{
+			Key:      key,
+			KeyID:    "k1",
+			Issuer:   sconfig.Issuer,
+			Audience: []string{"https://api.example.com"},
+		}
+
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("grant_type", string(ClientCredentialsGrant))
+		req.Form.Set("scope", "read")
+
+		if tr := server.HandleTokenRequest(resp, req); tr != nil {
+			tr.Authorized = true
+			server.FinishTokenRequest(resp, req, tr)
+		}
+
+		if resp.IsError {
+			t.Fatalf("Should not be an error (%T): %v", key, resp.InternalError)
+		}
+
+		token, _ := resp.Output["access_token"].(string)
+		v := &JWTAccessTokenValidator{
+			Issuer:   sconfig.Issuer,
+			Audience: "https://api.example.com",
+			Keys:     &JSONWebKeySet{Keys: []JSONWebKey{{Key: publicKey(key), KeyID: "k1"}}},
+		}
+		claims, err := v.ValidateAccessToken(token)
+		if err != nil {
+			t.Fatalf("Should not be an error (%T): %v", key, err)
+		}
+
+		if claims.String("sub") != "1234" || claims.String("client_id") != "1234" || claims.String("scope") != "read" {
+			t.Fatalf("Unexpected claims (%T): %v", key, claims)
+		}
+
+		// tokens can still be loaded from storage
+		if ag, err := server.Storage.LoadAccessGrant(token); err != nil || ag == nil {
+			t.Fatalf("Access grant should be saved (%T): %v", key, err)
+		}
+	}
+}
+
+func TestAccessTokenGenJWTUnsupportedKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := &AccessTokenGenJWT{Key: key, Issuer: "http://localhost:14000"}
+	if _, _, err = gen.GenerateAccessToken(&AccessGrant{Client: &DefaultClient{ID: "1234"}}, false); err == nil {
+		t.Fatalf("ES384 keys should not be supported")
+	}
+}
+
+func TestJWTAccessTokenValidator(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(c JWTClaims) JWTClaims {
+		ret := JWTClaims{
+			"iss":       "http://localhost:14000",
+			"sub":       "user",
+			"aud":       "https://api.example.com",
+			"exp":       now.Add(time.Minute).Unix(),
+			"iat":       now.Unix(),
+			"jti":       "j1",
+			"client_id": "1234",
+		}
+		for k, v := range c {
+			ret[k] = v
+		}
+		return ret
+	}
+	sign := func(key interface{}, alg, typ string, c JWTClaims) string {
+		token, err := signJWT(key, alg, map[string]interface{}{"typ": typ}, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	v := &JWTAccessTokenValidator{
+		Issuer:   "http://localhost:14000",
+		Audience: "https://api.example.com",
+		Keys:     &JSONWebKeySet{Keys: []JSONWebKey{{Key: &key.PublicKey}, {Key: []byte("secret")}}},
+	}
+
+	var tests = []struct {
+		token string
+		valid bool
+	}{
+		{sign(key, AlgES256, "at+jwt", claims(nil)), true},
+		{sign(key, AlgES256, "application/at+jwt", claims(nil)), true},
+		{sign(key, AlgES256, "JWT", claims(nil)), false},
+		{sign([]byte("secret"), AlgHS256, "at+jwt", claims(nil)), false},
+		{sign(key, AlgES256, "at+jwt", claims(JWTClaims{"iss": "http://other"})), false},
+		{sign(key, AlgES256, "at+jwt", claims(JWTClaims{"aud": "https://other.example.com"})), false},
+		{sign(key, AlgES256, "at+jwt", claims(JWTClaims{"exp": now.Add(-time.Hour).Unix()})), false},
+		{sign(key, AlgES256, "at+jwt", claims(JWTClaims{"client_id": ""})), false},
+	}
+
+	for i, tt := range tests {
+		_, err := v.ValidateAccessToken(tt.token)
+		if tt.valid && err != nil {
+			t.Errorf("Should not be an error (%d): %v", i, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("Token should be invalid (%d)", i)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// tokenAudiences returns the audiences identifying the token endpoint in JWT
+// assertions, see:
+// http://tools.ietf.org/html/rfc7523#section-3
+func (s *Server) tokenAudiences() []string {
+	return []string{s.Config.Issuer, s.Config.Endpoints.Token}
+}
+
+// saveJTI saves the JWT id until expiration, returning an error if it was
+// used before.
+func saveJTI(storage Storage, id string, expiration time.Time) error {
+	rs, ok := storage.(ReplayStorage)
+	if !ok {
+		return errors.New("storage does not support replay detection")
+	}
+
+	ok, err := rs.SaveJTI(id, expiration)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("jwt jti replayed")
+	}
+
+	return nil
+}
+
+// checkJTI checks that the "jti" claim has not been used before, saving it
+// until the JWT expires.
+func checkJTI(storage Storage, claims JWTClaims) error {
+	jti := claims.String("jti")
+	if jti == "" {
+		return errors.New("jwt jti required")
+	}
+
+	exp, _ := claims.Time("exp")
+	return saveJTI(storage, claims.String("iss")+" "+jti, exp.Add(jwtLeeway))
+}
+
+// validateAssertion validates the claims of a JWT assertion whose signature
+// has been verified, see:
+// http://tools.ietf.org/html/rfc7523#section-3
+func (s *Server) validateAssertion(storage Storage, claims JWTClaims) error {
+	if claims.String("iss") == "" {
+		return errors.New("jwt iss required")
+	}
+	if claims.String("sub") == "" {
+		return errors.New("jwt sub required")
+	}
+	if !claims.HasAudience(s.tokenAudiences()...) {
+		return errors.New("jwt aud does not identify the server")
+	}
+	if err := claims.validateTimes(s.Now()); err != nil {
+		return err
+	}
+	return checkJTI(storage, claims)
+}
+
+func (s *Server) handleJWTBearerRequest(w *Response, r *http.Request) *TokenRequest {
+	// generate access token
+	ret := &TokenRequest{
+		GrantType:       JWTBearerGrant,
+		Scope:           r.Form.Get("scope"),
+		Assertion:       r.Form.Get("assertion"),
+		GenerateRefresh: false,
+		Expiration:      s.Config.AccessExpiration,
+	}
+
+	// "assertion" is required
+	if ret.Assertion == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients cannot use the jwt bearer grant
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use the jwt bearer grant")
+		return nil
+	}
+
+	// must be signed by a trusted issuer
+	t, err := parseJWT(ret.Assertion)
+	if err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+	keys, ok := s.Config.TrustedIssuers[t.Claims.String("iss")]
+	if !ok {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("jwt issuer not trusted")
+		return nil
+	}
+	if err = t.verifyWithKeySet(keys); err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+
+	// must have valid claims
+	if err = s.validateAssertion(w.Storage, t.Claims); err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+
+	// set rest of data
+	ret.AssertionClaims = t.Claims
+	ret.Subject = t.Claims.String("sub")
+	ret.RedirectURI = firstURI(ret.Client.GetRedirectURI(), s.Config.RedirectURISeparator)
+
+	return ret
+}
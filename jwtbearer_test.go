@@ -0,0 +1,101 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestJWTBearer(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.Issuer = "http://localhost:14000"
+	sconfig.AllowedGrantTypes = []GrantType{JWTBearerGrant}
+	sconfig.TrustedIssuers = map[string]*JSONWebKeySet{
+		"http://workload": {Keys: []JSONWebKey{{Key: &key.PublicKey, KeyID: "w1"}}},
+	}
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	now := time.Now()
+	claims := func(c JWTClaims) JWTClaims {
+		ret := JWTClaims{
+			"iss": "http://workload",
+			"sub": "batch",
+			"aud": "http://localhost:14000",
+			"exp": now.Add(time.Minute).Unix(),
+			"jti": "j1",
+		}
+		for k, v := range c {
+			ret[k] = v
+		}
+		return ret
+	}
+
+	var tests = []struct {
+		claims JWTClaims
+		valid  bool
+	}{
+		{claims(nil), true},
+		{claims(nil), false}, // replayed jti
+		{claims(JWTClaims{"jti": "j2", "iss": "http://other"}), false},
+		{claims(JWTClaims{"jti": "j3", "aud": "http://other"}), false},
+		{claims(JWTClaims{"jti": "j4", "exp": now.Add(-time.Hour).Unix()}), false},
+		{claims(JWTClaims{"jti": "j5", "nbf": now.Add(time.Hour).Unix()}), false},
+		{claims(JWTClaims{"jti": "j6", "sub": ""}), false},
+		{claims(JWTClaims{"jti": ""}), false},
+	}
+
+	for i, tt := range tests {
+		assertion, err := signJWT(key, AlgES256, map[string]interface{}{"kid": "w1"}, tt.claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("grant_type", string(JWTBearerGrant))
+		req.Form.Set("assertion", assertion)
+
+		tr := server.HandleTokenRequest(resp, req)
+		if !tt.valid {
+			if tr != nil || resp.ErrorType != ErrInvalidGrant.Type {
+				t.Errorf("Expected invalid grant (%d), got: %s", i, resp.ErrorType)
+			}
+			continue
+		}
+
+		if tr == nil {
+			t.Fatalf("Expected valid grant (%d), got: %s", i, resp.InternalError)
+		}
+
+		if tr.Subject != "batch" {
+			t.Fatalf("Unexpected subject: %s", tr.Subject)
+		}
+
+		tr.Authorized = true
+		server.FinishTokenRequest(resp, req, tr)
+
+		if resp.IsError {
+			t.Fatalf("Should not be an error: %v", resp.InternalError)
+		}
+
+		if d := resp.Output["access_token"]; d != "1" {
+			t.Fatalf("Unexpected access token: %s", d)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// JWKSPath is the well-known path of the server JSON Web Key Set.
+const JWKSPath = "/.well-known/jwks.json"
+
+// KeyState is the state of a Key in a KeySet.
+type KeyState string
+
+const (
+	// KeyStateNext is the state of keys that will be activated by the next
+	// rotation. Next keys are published, so that relying parties have them
+	// before the first token is signed with them.
+	KeyStateNext KeyState = "next"
+
+	// KeyStateActive is the state of the key used for signing.
+	KeyStateActive KeyState = "active"
+
+	// KeyStateRetired is the state of keys no longer used for signing.
+	// Retired keys are published for the KeySet RetirementPeriod, so that
+	// tokens signed before the rotation can still be verified.
+	KeyStateRetired KeyState = "retired"
+)
+
+// Key is a signing key of a KeySet.
+type Key struct {
+	// JWK is the private key, with its key id ("kid").
+	JWK JSONWebKey
+
+	// State is the key state.
+	State KeyState
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+
+	// ActivatedAt is the time the key became active. Zero for next keys.
+	ActivatedAt time.Time
+
+	// RetiredAt is the time the key was retired. Zero for next and active
+	// keys.
+	RetiredAt time.Time
+}
+
+// KeySet manages the signing keys of the server, rotating the active key on
+// schedule.
+//
+// Rotation is checked whenever the active key is used, so no background
+// process is needed: once the active key has been active for the
+// RotationPeriod, the next key becomes active, the active key is retired and
+// a new next key is generated.
+type KeySet struct {
+	// Storage persists the keys. If nil, keys are only kept in memory.
+	Storage KeyStorage
+
+	// GenerateKey generates new keys on rotation. If nil, ECDSA P-256 keys
+	// are generated.
+	GenerateKey func() (interface{}, error)
+
+	// RotationPeriod is how long a key stays active. Zero disables
+	// scheduled rotation.
+	RotationPeriod time.Duration
+
+	// RetirementPeriod is how long retired keys are published, which should
+	// be at least the longest lifetime of signed tokens. If zero, retired
+	// keys are removed by the next rotation.
+	RetirementPeriod time.Duration
+
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	keys []*Key
+}
+
+// NewKeySet creates a KeySet, loading the keys saved in storage. Storage can
+// be nil.
+func NewKeySet(storage KeyStorage, rotationPeriod time.Duration) (*KeySet, error) {
+	ks := &KeySet{
+		Storage:          storage,
+		RotationPeriod:   rotationPeriod,
+		RetirementPeriod: rotationPeriod,
+	}
+	if err := ks.load(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// load loads the keys saved in storage, replacing the keys of the set, so
+// that rotations by other servers sharing the storage are picked up.
+func (ks *KeySet) load() error {
+	if ks.Storage == nil {
+		return nil
+	}
+	keys, err := ks.Storage.LoadKeys()
+	if err != nil {
+		return err
+	}
+	ks.keys = keys
+	return nil
+}
+
+// now returns the current time.
+func (ks *KeySet) now() time.Time {
+	if ks.Now != nil {
+		return ks.Now()
+	}
+	return time.Now()
+}
+
+// save saves k to storage.
+func (ks *KeySet) save(k *Key) error {
+	if ks.Storage == nil {
+		return nil
+	}
+	return ks.Storage.SaveKey(k)
+}
+
+// find returns the first key in state.
+func (ks *KeySet) find(state KeyState) *Key {
+	for _, k := range ks.keys {
+		if k.State == state {
+			return k
+		}
+	}
+	return nil
+}
+
+// add adds key to the set, retiring the active key when adding an active
+// key.
+func (ks *KeySet) add(key interface{}, kid string, state KeyState) (*Key, error) {
+	algs := keyAlgs(key)
+	if len(algs) == 0 {
+		return nil, errors.New("unsupported signing key")
+	}
+	if _, ok := key.([]byte); ok {
+		return nil, errors.New("symmetric keys cannot be published")
+	}
+
+	now := ks.now()
+	k := &Key{
+		JWK:       JSONWebKey{Key: key, KeyID: kid, Algorithm: algs[0], Use: "sig"},
+		State:     state,
+		CreatedAt: now,
+	}
+	if k.JWK.KeyID == "" {
+		var err error
+		if k.JWK.KeyID, err = k.JWK.Thumbprint(); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range ks.keys {
+		if e.JWK.KeyID == k.JWK.KeyID {
+			return nil, errors.New("duplicate key id: " + k.JWK.KeyID)
+		}
+	}
+
+	switch state {
+	case KeyStateActive:
+		if active := ks.find(KeyStateActive); active != nil {
+			active.State, active.RetiredAt = KeyStateRetired, now
+			if err := ks.save(active); err != nil {
+				return nil, err
+			}
+		}
+		k.ActivatedAt = now
+	case KeyStateRetired:
+		k.RetiredAt = now
+	case KeyStateNext:
+	default:
+		return nil, errors.New("invalid key state: " + string(state))
+	}
+
+	if err := ks.save(k); err != nil {
+		return nil, err
+	}
+	ks.keys = append(ks.keys, k)
+	return k, nil
+}
+
+// AddKey adds the private key to the set in state. If kid is blank, the key
+// thumbprint is used as the key id. Adding an active key retires the current
+// active key.
+func (ks *KeySet) AddKey(key interface{}, kid string, state KeyState) (*Key, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.add(key, kid, state)
+}
+
+// generate generates a new key in state.
+func (ks *KeySet) generate(state KeyState) error {
+	gen := ks.GenerateKey
+	if gen == nil {
+		gen = func() (interface{}, error) {
+			return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		}
+	}
+	key, err := gen()
+	if err != nil {
+		return err
+	}
+	_, err = ks.add(key, "", state)
+	return err
+}
+
+// removeExpired removes the retired keys past the retirement period.
+func (ks *KeySet) removeExpired(now time.Time) error {
+	var keys []*Key
+	for _, k := range ks.keys {
+		if k.State == KeyStateRetired && !now.Before(k.RetiredAt.Add(ks.RetirementPeriod)) {
+			if ks.Storage != nil {
+				if err := ks.Storage.RemoveKey(k.JWK.KeyID); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		keys = append(keys, k)
+	}
+	ks.keys = keys
+	return nil
+}
+
+// rotate activates the next key, retiring the active key, and generates a
+// new next key.
+func (ks *KeySet) rotate() error {
+	now := ks.now()
+	if err := ks.removeExpired(now); err != nil {
+		return err
+	}
+
+	if active := ks.find(KeyStateActive); active != nil {
+		active.State, active.RetiredAt = KeyStateRetired, now
+		if err := ks.save(active); err != nil {
+			return err
+		}
+	}
+
+	if next := ks.find(KeyStateNext); next != nil {
+		next.State, next.ActivatedAt = KeyStateActive, now
+		if err := ks.save(next); err != nil {
+			return err
+		}
+	} else if err := ks.generate(KeyStateActive); err != nil {
+		return err
+	}
+
+	return ks.generate(KeyStateNext)
+}
+
+// Rotate rotates the keys immediately: the next key becomes active, the
+// active key is retired, and a new next key is generated.
+func (ks *KeySet) Rotate() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.load(); err != nil {
+		return err
+	}
+	return ks.rotate()
+}
+
+// rotationDue determines if the active key must be rotated.
+func (ks *KeySet) rotationDue(active *Key) bool {
+	return active == nil || (ks.RotationPeriod > 0 && !ks.now().Before(active.ActivatedAt.Add(ks.RotationPeriod)))
+}
+
+// ActiveKey returns the active signing key, rotating the keys first if the
+// rotation is due. Keys are generated if the set has no active key. Before
+// rotating, the keys are reloaded from storage, in case they were already
+// rotated by another server sharing the storage.
+func (ks *KeySet) ActiveKey() (*JSONWebKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	active := ks.find(KeyStateActive)
+	if ks.rotationDue(active) {
+		if err := ks.load(); err != nil {
+			return nil, err
+		}
+		if active = ks.find(KeyStateActive); ks.rotationDue(active) {
+			if err := ks.rotate(); err != nil {
+				return nil, err
+			}
+			active = ks.find(KeyStateActive)
+		}
+	}
+
+	jwk := active.JWK
+	return &jwk, nil
+}
+
+// Algorithm returns the signing algorithm of the active key, without
+// rotating or generating keys. Blank if the set has no active key.
+func (ks *KeySet) Algorithm() string {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if active := ks.find(KeyStateActive); active != nil {
+		return signingAlg(&active.JWK)
+	}
+	return ""
+}
+
+// PublicKeys returns the public keys of the next, active and retired keys.
+// The keys are reloaded from storage first, in case they were rotated by
+// another server sharing the storage, and generated if the set has no active
+// or next key, so keys are published before they are used.
+func (ks *KeySet) PublicKeys() (*JSONWebKeySet, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if err := ks.load(); err != nil {
+		return nil, err
+	}
+	if ks.rotationDue(ks.find(KeyStateActive)) {
+		if err := ks.rotate(); err != nil {
+			return nil, err
+		}
+	} else if ks.find(KeyStateNext) == nil {
+		if err := ks.generate(KeyStateNext); err != nil {
+			return nil, err
+		}
+	}
+
+	now := ks.now()
+	ret := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, k := range ks.keys {
+		if k.State == KeyStateRetired && !now.Before(k.RetiredAt.Add(ks.RetirementPeriod)) {
+			continue
+		}
+		jwk := k.JWK
+		jwk.Key = publicKey(jwk.Key)
+		ret.Keys = append(ret.Keys, jwk)
+	}
+	return ret, nil
+}
+
+// ParsePEMKey parses a PEM encoded private key, one of PKCS #1 RSA ("RSA
+// PRIVATE KEY"), SEC 1 EC ("EC PRIVATE KEY") or PKCS #8 ("PRIVATE KEY") RSA,
+// ECDSA or Ed25519 keys.
+func ParsePEMKey(buf []byte) (interface{}, error) {
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("no pem data")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+			return k, nil
+		}
+		return nil, errors.New("unsupported pkcs8 private key")
+	}
+	return nil, errors.New("unsupported pem type: " + block.Type)
+}
+
+// LoadPEMKeyFile loads the PEM encoded private key in the named file (see
+// ParsePEMKey).
+func LoadPEMKeyFile(name string) (interface{}, error) {
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePEMKey(buf)
+}
+
+// signingKey returns the key used to sign the tokens issued by the server:
+// the active key of the server KeySet, or the server SigningKey.
+func (s *Server) signingKey() (*JSONWebKey, error) {
+	if s.KeySet != nil {
+		return s.KeySet.ActiveKey()
+	}
+	if s.SigningKey == nil {
+		return nil, errors.New("no signing key")
+	}
+	return s.SigningKey, nil
+}
+
+// hasSigningKey determines if the server has a key to sign tokens.
+func (s *Server) hasSigningKey() bool {
+	return s.KeySet != nil || s.SigningKey != nil
+}
+
+// publicKeys returns the published keys of the server.
+func (s *Server) publicKeys() (*JSONWebKeySet, error) {
+	if s.KeySet != nil {
+		return s.KeySet.PublicKeys()
+	}
+	if s.SigningKey == nil {
+		return nil, errors.New("no signing key")
+	}
+	if _, ok := s.SigningKey.Key.([]byte); ok {
+		return nil, errors.New("symmetric keys cannot be published")
+	}
+	jwk := *s.SigningKey
+	jwk.Key = publicKey(jwk.Key)
+	return &JSONWebKeySet{Keys: []JSONWebKey{jwk}}, nil
+}
+
+// HandleJWKSRequest is the http.HandlerFunc for serving the public keys of
+// the server, normally served at JWKSPath on the server. Only public keys are
+// served.
+func (s *Server) HandleJWKSRequest(w *Response, r *http.Request) {
+	if r.Method != "GET" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be GET")
+		return
+	}
+
+	keys, err := s.publicKeys()
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	w.Output["keys"] = keys.Keys
+}
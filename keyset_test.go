@@ -0,0 +1,302 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKeySetRotation(t *testing.T) {
+	now := time.Now()
+	storage := NewTestStorage(t)
+	ks, err := NewKeySet(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Now = func() time.Time { return now }
+
+	first, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.KeyID == "" {
+		t.Fatalf("Key id should be set")
+	}
+	if len(storage.Keys) != 2 {
+		t.Fatalf("Expected active and next keys to be saved, got: %d", len(storage.Keys))
+	}
+
+	// not due
+	now = now.Add(30 * time.Minute)
+	key, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyID != first.KeyID {
+		t.Fatalf("Key should not have been rotated")
+	}
+
+	// due
+	now = now.Add(30 * time.Minute)
+	second, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.KeyID == first.KeyID {
+		t.Fatalf("Key should have been rotated")
+	}
+	if k := storage.Keys[first.KeyID]; k == nil || k.State != KeyStateRetired {
+		t.Fatalf("First key should be retired: %v", k)
+	}
+
+	jwks, err := ks.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("Expected 3 published keys, got: %d", len(jwks.Keys))
+	}
+	for _, k := range jwks.Keys {
+		if _, ok := k.Key.(*ecdsa.PublicKey); !ok {
+			t.Fatalf("Published key %s should be a public key: %T", k.KeyID, k.Key)
+		}
+	}
+
+	// retired key past the retirement period
+	now = now.Add(time.Hour)
+	if _, err = ks.ActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := storage.Keys[first.KeyID]; ok {
+		t.Fatalf("First key should have been removed")
+	}
+	if jwks, err = ks.PublicKeys(); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range jwks.Keys {
+		if k.KeyID == first.KeyID {
+			t.Fatalf("First key should not be published")
+		}
+	}
+
+	// reload from storage
+	loaded, err := NewKeySet(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.Now = ks.Now
+	if key, err = loaded.ActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := ks.ActiveKey(); key.KeyID != active.KeyID {
+		t.Fatalf("Loaded active key %s should be %s", key.KeyID, active.KeyID)
+	}
+}
+
+func TestKeySetSharedStorage(t *testing.T) {
+	storage := NewTestStorage(t)
+	ks, err := NewKeySet(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewKeySet(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// algorithm doesn't generate keys
+	if alg := ks.Algorithm(); alg != "" || len(storage.Keys) != 0 {
+		t.Fatalf("Keys should not have been generated: %q %d", alg, len(storage.Keys))
+	}
+
+	key, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alg := ks.Algorithm(); alg != AlgES256 {
+		t.Fatalf("Unexpected algorithm: %q", alg)
+	}
+
+	// keys generated by another server are loaded instead of rotated
+	otherKey, err := other.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherKey.KeyID != key.KeyID || len(storage.Keys) != 2 {
+		t.Fatalf("Active key %s should be %s (%d keys)", otherKey.KeyID, key.KeyID, len(storage.Keys))
+	}
+
+	// keys rotated by another server are published
+	if err = ks.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := other.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != len(storage.Keys) || len(jwks.Keys) != 3 {
+		t.Fatalf("Expected %d published keys, got: %d", len(storage.Keys), len(jwks.Keys))
+	}
+}
+
+func TestKeySetPublishBeforeUse(t *testing.T) {
+	storage := NewTestStorage(t)
+	ks, err := NewKeySet(storage, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := ks.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 2 || len(storage.Keys) != 2 {
+		t.Fatalf("Expected active and next keys to be published, got: %d", len(jwks.Keys))
+	}
+
+	// the published keys are used
+	key, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := storage.Keys[key.KeyID]; k == nil || k.State != KeyStateActive {
+		t.Fatalf("Active key %s should have been published", key.KeyID)
+	}
+}
+
+func TestKeySetAddKey(t *testing.T) {
+	ks, err := NewKeySet(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key2, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ks.AddKey(key1, "key1", KeyStateActive); err != nil {
+		t.Fatal(err)
+	}
+	k2, err := ks.AddKey(key2, "", KeyStateActive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ks.AddKey([]byte("secret"), "key3", KeyStateNext); err == nil {
+		t.Fatalf("Symmetric key should not be added")
+	}
+	if _, err = ks.AddKey(key1, "key1", KeyStateNext); err == nil {
+		t.Fatalf("Duplicate key id should not be added")
+	}
+
+	active, err := ks.ActiveKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.KeyID != k2.JWK.KeyID || signingAlg(active) != AlgEdDSA {
+		t.Fatalf("Unexpected active key: %s %s", active.KeyID, signingAlg(active))
+	}
+	if ks.find(KeyStateRetired).JWK.KeyID != "key1" {
+		t.Fatalf("key1 should be retired")
+	}
+}
+
+func TestParsePEMKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecBuf, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edBuf, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		typ string
+		buf []byte
+		alg string
+	}{
+		{"RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), AlgRS256},
+		{"EC PRIVATE KEY", ecBuf, AlgES256},
+		{"PRIVATE KEY", edBuf, AlgEdDSA},
+	}
+
+	for i, tt := range tests {
+		key, err := ParsePEMKey(pem.EncodeToMemory(&pem.Block{Type: tt.typ, Bytes: tt.buf}))
+		if err != nil {
+			t.Errorf("Could not parse key (%d): %v", i, err)
+			continue
+		}
+		if alg := signingAlg(&JSONWebKey{Key: key}); alg != tt.alg {
+			t.Errorf("Expected alg %s (%d), got: %s", tt.alg, i, alg)
+		}
+	}
+
+	if _, err = ParsePEMKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")})); err == nil {
+		t.Fatalf("Certificate should not be parsed")
+	}
+}
+
+func TestJWKSRequest(t *testing.T) {
+	ks, err := NewKeySet(nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(NewConfig(), NewTestStorage(t))
+	server.KeySet = ks
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000"+JWKSPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sign an id token with the active key
+	client := server.Storage.(*MemStorage).Clients["1234"]
+	token, err := server.generateIDToken(&IDToken{Client: client, Subject: "user", ExpiresIn: 3600, CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.HandleJWKSRequest(resp, req)
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	keys, ok := resp.Output["keys"].([]JSONWebKey)
+	if !ok || len(keys) != 2 {
+		t.Fatalf("Unexpected keys: %v", resp.Output["keys"])
+	}
+
+	jt, err := parseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = jt.verifyWithKeySet(&JSONWebKeySet{Keys: keys}); err != nil {
+		t.Fatalf("ID token should verify with the published keys: %v", err)
+	}
+}
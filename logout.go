@@ -0,0 +1,462 @@
+package oauthlib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// BackchannelLogoutEvent is the event of back-channel logout tokens, see:
+// http://openid.net/specs/openid-connect-backchannel-1_0.html#LogoutToken
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// ErrSessionNotFound is the error returned by SessionStorage LoadSession if
+// the session does not exist, such as when it has ended.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a login session of an end-user at the server, recording the
+// clients the end-user logged in to and the tokens issued to them during the
+// session.
+type Session struct {
+	// ID is the session id ("sid").
+	ID string
+
+	// Subject is the end-user of the session.
+	Subject string
+
+	// Clients are the ids of the clients the end-user logged in to.
+	Clients []string
+
+	// AccessTokens are the access tokens issued during the session, revoked
+	// with their refresh tokens when the session ends.
+	AccessTokens []string
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+}
+
+// LogoutRequest is a RP-initiated logout request, sent by a client to the
+// end-session endpoint to log the end-user out of the server, see:
+// http://openid.net/specs/openid-connect-rpinitiated-1_0.html
+type LogoutRequest struct {
+	// Client is the client requesting the logout. Can be nil
+	Client Client
+
+	// IDTokenHint is the ID token previously issued to the client, hinting
+	// the end-user and session. Can be blank
+	IDTokenHint string
+
+	// Subject is the end-user from the IDTokenHint. Can be blank
+	Subject string
+
+	// SessionID is the session to end, from the "sid" claim of the
+	// IDTokenHint. If blank, set to the end-user's current session before
+	// calling FinishLogoutRequest.
+	SessionID string
+
+	// PostLogoutRedirectURI is the URI the end-user is redirected to after
+	// logout. Can be blank
+	PostLogoutRedirectURI string
+
+	// State is the passed state in the request.
+	State string
+
+	// UILocales are the preferred languages of the logout page. Can be blank
+	UILocales string
+
+	// Authorized toggles if the end-user confirmed the logout.
+	Authorized bool
+
+	// FrontchannelLogoutURIs are the front-channel logout URIs of the clients
+	// of the ended session. Set by FinishLogoutRequest.
+	FrontchannelLogoutURIs []string
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+
+	// HttpRequest *http.Request for special use
+	HttpRequest *http.Request
+}
+
+// LogoutNotifier sends logout tokens to the back-channel logout URIs of
+// clients.
+type LogoutNotifier interface {
+	// NotifyLogout sends the logout token to the back-channel logout URI.
+	NotifyLogout(uri, logoutToken string) error
+}
+
+// LogoutNotifierDefault is the default logout notifier, posting the logout
+// tokens as a form.
+type LogoutNotifierDefault struct {
+	// Client is the HTTP client used to send logout tokens. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NotifyLogout posts the logout token to uri, see:
+// http://openid.net/specs/openid-connect-backchannel-1_0.html#BCRequest
+func (a *LogoutNotifierDefault) NotifyLogout(uri, logoutToken string) error {
+	hc := a.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.PostForm(uri, url.Values{"logout_token": {logoutToken}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("back-channel logout uri returned status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// getSessionStorage returns storage as SessionStorage.
+func getSessionStorage(storage Storage) (SessionStorage, error) {
+	ss, ok := storage.(SessionStorage)
+	if !ok {
+		return nil, errors.New("storage does not support sessions")
+	}
+	return ss, nil
+}
+
+// addToSession adds the client and the access token to the session sid of
+// the end-user subject, creating the session if it does not exist. Sessions
+// are not tracked if the storage does not support them.
+func (s *Server) addToSession(storage Storage, sid, subject string, client Client, accessToken string) error {
+	ss, ok := storage.(SessionStorage)
+	if !ok {
+		return nil
+	}
+
+	session, err := ss.LoadSession(sid)
+	switch {
+	case err == ErrSessionNotFound:
+		session = &Session{
+			ID:        sid,
+			Subject:   subject,
+			CreatedAt: s.Now(),
+		}
+	case err != nil:
+		return err
+	}
+	if session.Subject != subject {
+		return errors.New("session belongs to another end-user")
+	}
+
+	found := false
+	for _, id := range session.Clients {
+		if id == client.GetID() {
+			found = true
+			break
+		}
+	}
+	if !found {
+		session.Clients = append(session.Clients, client.GetID())
+	}
+	if accessToken != "" {
+		session.AccessTokens = append(session.AccessTokens, accessToken)
+	}
+	return ss.SaveSession(session)
+}
+
+// checkSession checks that the session sid, that a grant was issued in, has
+// not ended. Grants issued outside of a session are not checked, nor are
+// sessions if the storage does not support them.
+func checkSession(storage Storage, sid string) error {
+	ss, ok := storage.(SessionStorage)
+	if !ok || sid == "" {
+		return nil
+	}
+	_, err := ss.LoadSession(sid)
+	return err
+}
+
+// validatePostLogoutRedirectURI validates that uri is one of the registered
+// post logout redirect URIs of client.
+func validatePostLogoutRedirectURI(client Client, uri string) error {
+	var uris []string
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil {
+			uris = md.PostLogoutRedirectURIs
+		}
+	}
+	if len(uris) == 0 {
+		return errors.New("client has no post logout redirect uris")
+	}
+	return validateURIList(strings.Join(uris, "\n"), uri, "\n")
+}
+
+// HandleLogoutRequest is the http.HandlerFunc for handling RP-initiated
+// logout requests at the end-session endpoint.
+//
+// The end-user should be asked to confirm the logout, unless the request has
+// an id_token_hint of the end-user's current session.
+func (s *Server) HandleLogoutRequest(w *Response, r *http.Request) *LogoutRequest {
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	ret := &LogoutRequest{
+		IDTokenHint:           r.Form.Get("id_token_hint"),
+		PostLogoutRedirectURI: r.Form.Get("post_logout_redirect_uri"),
+		State:                 r.Form.Get("state"),
+		UILocales:             r.Form.Get("ui_locales"),
+		HttpRequest:           r,
+	}
+
+	// id token hints must be issued by the server, expired tokens are
+	// accepted
+	clientID := r.Form.Get("client_id")
+	if ret.IDTokenHint != "" {
+		claims, err := s.verifyIDTokenHint(ret.IDTokenHint)
+		if err != nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+		ret.Subject = claims.String("sub")
+		ret.SessionID = claims.String("sid")
+
+		if aud := claims.Audience(); clientID == "" && len(aud) == 1 {
+			clientID = aud[0]
+		}
+		if !claims.HasAudience(clientID) {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = errors.New("client id does not match id token hint")
+			return nil
+		}
+	}
+
+	// load client
+	if clientID != "" {
+		if ret.Client, err = w.Storage.GetClient(clientID); err != nil || ret.Client == nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+	}
+
+	// post logout redirect uri must be registered by the client
+	if ret.PostLogoutRedirectURI != "" {
+		if ret.Client == nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = errors.New("client required for post logout redirect uri")
+			return nil
+		}
+		if err = validatePostLogoutRedirectURI(ret.Client, ret.PostLogoutRedirectURI); err != nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+	}
+
+	return ret
+}
+
+// FinishLogoutRequest finalizes the request handled by HandleLogoutRequest,
+// ending the session and redirecting to the post logout redirect URI.
+//
+// The clients of the session are logged out through the back-channel, and
+// their front-channel logout URIs are set on the LogoutRequest and Response,
+// rendered as iframes by WriteJSON. Failed back-channel logout notifications
+// don't fail the request; the first failure is set as the InternalError of
+// the Response.
+func (s *Server) FinishLogoutRequest(w *Response, r *http.Request, lr *LogoutRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	if !lr.Authorized {
+		w.SetError(ErrAccessDenied)
+		return
+	}
+
+	// end session, sessions already ended are ignored
+	if ss, ok := w.Storage.(SessionStorage); ok && lr.SessionID != "" {
+		session, err := ss.LoadSession(lr.SessionID)
+		if err != nil && err != ErrSessionNotFound {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return
+		}
+		if err == nil {
+			if lr.Subject != "" && lr.Subject != session.Subject {
+				w.SetError(ErrInvalidRequest)
+				w.InternalError = errors.New("id token hint subject does not match session")
+				return
+			}
+			if err = s.removeSession(w.Storage, session); err != nil {
+				w.SetError(ErrServerError)
+				w.InternalError = err
+				return
+			}
+			lr.FrontchannelLogoutURIs, w.InternalError = s.notifyLogout(w.Storage, session)
+		}
+	}
+
+	// output data
+	w.FrontchannelLogoutURIs = lr.FrontchannelLogoutURIs
+	if lr.PostLogoutRedirectURI != "" {
+		w.ResponseType = REDIRECT
+		w.URL = lr.PostLogoutRedirectURI
+		if lr.State != "" {
+			w.Output["state"] = lr.State
+		}
+	}
+}
+
+// EndSession ends the session sid, logging the end-user out of the clients
+// of the session. Returns the front-channel logout URIs of the clients, to be
+// rendered as iframes in the end-user's user agent.
+//
+// Failed back-channel logout notifications don't stop the session from
+// ending; the first failure is returned after all clients were notified.
+func (s *Server) EndSession(sid string) ([]string, error) {
+	ss, err := getSessionStorage(s.Storage)
+	if err != nil {
+		return nil, err
+	}
+	session, err := ss.LoadSession(sid)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.removeSession(s.Storage, session); err != nil {
+		return nil, err
+	}
+	return s.notifyLogout(s.Storage, session)
+}
+
+// removeSession removes the session, revoking the tokens issued during the
+// session.
+func (s *Server) removeSession(storage Storage, session *Session) error {
+	ss, err := getSessionStorage(storage)
+	if err != nil {
+		return err
+	}
+	if err = ss.RemoveSession(session.ID); err != nil {
+		return err
+	}
+
+	// tokens already revoked or refreshed are skipped
+	for _, token := range session.AccessTokens {
+		ret, err := storage.LoadAccessGrant(token)
+		if err != nil || ret == nil {
+			continue
+		}
+		if ret.RefreshToken != "" {
+			if err = storage.RemoveRefreshGrant(ret.RefreshToken); err != nil {
+				return err
+			}
+		}
+		if err = storage.RemoveAccessGrant(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyLogout sends logout tokens to the back-channel logout URIs of the
+// clients of the session, returning their front-channel logout URIs and the
+// first failed notification.
+func (s *Server) notifyLogout(storage Storage, session *Session) ([]string, error) {
+	var uris []string
+	var ret error
+	for _, id := range session.Clients {
+		client, err := storage.GetClient(id)
+		if err != nil || client == nil {
+			continue
+		}
+		c, ok := client.(ClientMetadataGetter)
+		if !ok {
+			continue
+		}
+		md := c.GetMetadata()
+		if md == nil {
+			continue
+		}
+
+		// see http://openid.net/specs/openid-connect-frontchannel-1_0.html#RPLogout
+		if md.FrontchannelLogoutURI != "" {
+			uri, err := s.frontchannelLogoutURI(md, session.ID)
+			if err != nil {
+				if ret == nil {
+					ret = err
+				}
+				continue
+			}
+			uris = append(uris, uri)
+		}
+
+		// see http://openid.net/specs/openid-connect-backchannel-1_0.html#Backchannel
+		if md.BackchannelLogoutURI != "" {
+			logoutToken, err := s.generateLogoutToken(client, session)
+			if err == nil {
+				err = s.LogoutNotifier.NotifyLogout(md.BackchannelLogoutURI, logoutToken)
+			}
+			if err != nil && ret == nil {
+				ret = err
+			}
+		}
+	}
+	return uris, ret
+}
+
+// frontchannelLogoutURI returns the front-channel logout URI of the client
+// for the session, with the "iss" and "sid" parameters if required.
+func (s *Server) frontchannelLogoutURI(md *ClientMetadata, sid string) (string, error) {
+	if !md.FrontchannelLogoutSessionRequired {
+		return md.FrontchannelLogoutURI, nil
+	}
+	u, err := url.Parse(md.FrontchannelLogoutURI)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("iss", s.Config.Issuer)
+	q.Set("sid", sid)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// generateLogoutToken generates the signed logout token of the session for
+// client, see:
+// http://openid.net/specs/openid-connect-backchannel-1_0.html#LogoutToken
+func (s *Server) generateLogoutToken(client Client, session *Session) (string, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := s.Now()
+	claims := JWTClaims{
+		"iss": s.Config.Issuer,
+		"sub": session.Subject,
+		"aud": client.GetID(),
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(s.Config.LogoutTokenExpiration) * time.Second).Unix(),
+		"jti": uuid.New(),
+		"sid": session.ID,
+		"events": map[string]interface{}{
+			BackchannelLogoutEvent: map[string]interface{}{},
+		},
+	}
+
+	header := map[string]interface{}{"typ": "logout+jwt"}
+	if key.KeyID != "" {
+		header["kid"] = key.KeyID
+	}
+	return signJWT(key.Key, signingAlg(key), header, claims)
+}
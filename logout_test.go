@@ -0,0 +1,418 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type testLogoutNotification struct {
+	uri         string
+	logoutToken string
+}
+
+type testLogoutNotifier []testLogoutNotification
+
+func (n *testLogoutNotifier) NotifyLogout(uri, logoutToken string) error {
+	*n = append(*n, testLogoutNotification{uri, logoutToken})
+	return nil
+}
+
+// testLogoutClients are the front-channel logout client "front" and the
+// back-channel logout client "back".
+var testLogoutClients = []*DefaultClient{
+	{
+		ID:          "front",
+		Secret:      "aabbccdd",
+		RedirectURI: "http://localhost:14000/appauth",
+		Metadata: ClientMetadata{
+			PostLogoutRedirectURIs:            []string{"http://localhost:14000/loggedout"},
+			FrontchannelLogoutURI:             "http://localhost:14000/frontchannel",
+			FrontchannelLogoutSessionRequired: true,
+		},
+	},
+	{
+		ID:          "back",
+		Secret:      "aabbccdd",
+		RedirectURI: "http://localhost:14000/appauth",
+		Metadata: ClientMetadata{
+			BackchannelLogoutURI: "https://localhost:14000/backchannel",
+		},
+	},
+}
+
+func TestLogout(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	notifier := &testLogoutNotifier{}
+	server.LogoutNotifier = notifier
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testLogoutClients {
+		storage.Clients[client.ID] = client
+	}
+
+	// login
+	var logins []*Response
+	for _, l := range []struct{ client, sid string }{{"front", "s1"}, {"back", "s1"}, {"back", "s2"}} {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{
+			"response_type": {"code"},
+			"client_id":     {l.client},
+			"scope":         {"openid"},
+		}
+		if ar := server.HandleAuthRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			ar.Subject = "user"
+			ar.SessionID = l.sid
+			server.FinishAuthRequest(resp, req, ar)
+		}
+		if resp.IsError {
+			t.Fatalf("Should not be an error: %v", resp.InternalError)
+		}
+
+		resp = doTestTokenRequest(t, server, l.client, url.Values{
+			"grant_type": {string(AuthorizationCodeGrant)},
+			"code":       {resp.Output["code"].(string)},
+		})
+		if resp.IsError {
+			t.Fatalf("Should not be an error: %v", resp.InternalError)
+		}
+		logins = append(logins, resp)
+	}
+	front, back, other := logins[0], logins[1], logins[2]
+
+	jt, err := parseJWT(front.Output["id_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jt.Claims.String("sid") != "s1" {
+		t.Fatalf("Unexpected id token sid: %v", jt.Claims["sid"])
+	}
+	if session := storage.Sessions["s1"]; session == nil || len(session.Clients) != 2 || len(session.AccessTokens) != 2 {
+		t.Fatalf("Unexpected session: %v", session)
+	}
+
+	// logout
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/logout", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"id_token_hint":            {front.Output["id_token"].(string)},
+		"post_logout_redirect_uri": {"http://localhost:14000/loggedout"},
+		"state":                    {"a"},
+	}
+	lr := server.HandleLogoutRequest(resp, req)
+	if lr == nil {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	lr.Authorized = true
+	server.FinishLogoutRequest(resp, req, lr)
+	if resp.IsError || resp.InternalError != nil {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	if lr.Subject != "user" || lr.SessionID != "s1" || lr.Client.GetID() != "front" {
+		t.Fatalf("Unexpected logout request: %v", lr)
+	}
+	if u, err := resp.GetRedirectURL(); err != nil || u != "http://localhost:14000/loggedout?state=a" {
+		t.Fatalf("Unexpected redirect url: %s", u)
+	}
+
+	// session tokens are revoked
+	if _, ok := storage.Sessions["s1"]; ok {
+		t.Fatalf("Session should have been removed")
+	}
+	for _, r := range []*Response{front, back} {
+		if _, ok := storage.AccessGrants[r.Output["access_token"].(string)]; ok {
+			t.Fatalf("Session access token should have been revoked")
+		}
+		if _, ok := storage.RefreshGrants[r.Output["refresh_token"].(string)]; ok {
+			t.Fatalf("Session refresh token should have been revoked")
+		}
+	}
+	if _, ok := storage.AccessGrants[other.Output["access_token"].(string)]; !ok {
+		t.Fatalf("Other session access token should not have been revoked")
+	}
+
+	// front-channel logout
+	if len(lr.FrontchannelLogoutURIs) != 1 || lr.FrontchannelLogoutURIs[0] != "http://localhost:14000/frontchannel?iss=http%3A%2F%2Flocalhost%3A14000&sid=s1" {
+		t.Fatalf("Unexpected front-channel logout uris: %v", lr.FrontchannelLogoutURIs)
+	}
+	rec := httptest.NewRecorder()
+	if err = WriteJSON(rec, resp); err != nil {
+		t.Fatal(err)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `<iframe src="http://localhost:14000/frontchannel?iss=http%3A%2F%2Flocalhost%3A14000&amp;sid=s1"`) ||
+		!strings.Contains(body, "loggedout?state=a") {
+		t.Fatalf("Unexpected logout page: %s", body)
+	}
+
+	// back-channel logout
+	if len(*notifier) != 1 || (*notifier)[0].uri != "https://localhost:14000/backchannel" {
+		t.Fatalf("Unexpected back-channel logout notifications: %v", *notifier)
+	}
+	if jt, err = parseJWT((*notifier)[0].logoutToken); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := server.publicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = jt.verifyWithKeySet(keys); err != nil {
+		t.Fatalf("Invalid logout token signature: %v", err)
+	}
+	c := jt.Claims
+	if c.String("iss") != "http://localhost:14000" || c.String("aud") != "back" || c.String("sub") != "user" || c.String("sid") != "s1" || c.String("jti") == "" {
+		t.Fatalf("Unexpected logout token claims: %v", c)
+	}
+	if events, ok := c["events"].(map[string]interface{}); !ok || events[BackchannelLogoutEvent] == nil {
+		t.Fatalf("Unexpected logout token events: %v", c["events"])
+	}
+	if _, ok := c["nonce"]; ok {
+		t.Fatalf("Logout token must not have a nonce")
+	}
+}
+
+func TestLogoutRefreshedToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	server.LogoutNotifier = &testLogoutNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testLogoutClients {
+		storage.Clients[client.ID] = client
+	}
+
+	// login
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"back"},
+		"scope":         {"openid"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		ar.Subject = "user"
+		ar.SessionID = "s1"
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	resp = doTestTokenRequest(t, server, "back", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {resp.Output["code"].(string)},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	// refresh
+	refresh := doTestTokenRequest(t, server, "back", url.Values{
+		"grant_type":    {string(RefreshTokenGrant)},
+		"refresh_token": {resp.Output["refresh_token"].(string)},
+	})
+	if refresh.IsError {
+		t.Fatalf("Should not be an error: %v", refresh.InternalError)
+	}
+	token := refresh.Output["access_token"].(string)
+	if ag := storage.AccessGrants[token]; ag.SessionID != "s1" {
+		t.Fatalf("Refreshed token should keep the session: %q", ag.SessionID)
+	}
+
+	if _, err = server.EndSession("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := storage.AccessGrants[token]; ok {
+		t.Fatalf("Refreshed access token should have been revoked")
+	}
+}
+
+// testUnavailableSessionStorage is storage failing to load sessions.
+type testUnavailableSessionStorage struct {
+	*MemStorage
+}
+
+func (s testUnavailableSessionStorage) LoadSession(id string) (*Session, error) {
+	return nil, errors.New("session storage unavailable")
+}
+
+func TestLogoutPendingCode(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	server.LogoutNotifier = &testLogoutNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testLogoutClients {
+		storage.Clients[client.ID] = client
+	}
+
+	// authorize, then end session before the code is exchanged
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"front"},
+		"scope":         {"openid"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		ar.Subject = "user"
+		ar.SessionID = "s1"
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	code := resp.Output["code"].(string)
+	if _, err = server.EndSession("s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = doTestTokenRequest(t, server, "front", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {code},
+	})
+	if resp.ErrorType != ErrInvalidGrant.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrInvalidGrant.Type, resp.ErrorType)
+	}
+	if _, ok := storage.Sessions["s1"]; ok {
+		t.Fatalf("Session should not have been recreated")
+	}
+}
+
+func TestLogoutSessionStorageError(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	server.LogoutNotifier = &testLogoutNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testLogoutClients {
+		storage.Clients[client.ID] = client
+	}
+	server.Storage = testUnavailableSessionStorage{storage}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"front"},
+		"scope":         {"openid"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		ar.Subject = "user"
+		ar.SessionID = "s1"
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.ErrorType != ErrServerError.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrServerError.Type, resp.ErrorType)
+	}
+}
+
+func TestLogoutInvalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.SigningKey = &JSONWebKey{Key: key}
+	server.LogoutNotifier = &testLogoutNotifier{}
+	storage := server.Storage.(*MemStorage)
+	for _, client := range testLogoutClients {
+		storage.Clients[client.ID] = client
+	}
+
+	// login
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"front"},
+		"scope":         {"openid"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		ar.Subject = "user"
+		ar.SessionID = "s1"
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	resp = doTestTokenRequest(t, server, "front", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {resp.Output["code"].(string)},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	idToken := resp.Output["id_token"].(string)
+
+	var tests = []url.Values{
+		{"id_token_hint": {"x.y.z"}},
+		{"id_token_hint": {idToken}, "client_id": {"back"}},
+		{"post_logout_redirect_uri": {"http://localhost:14000/loggedout"}},
+		{"client_id": {"front"}, "post_logout_redirect_uri": {"http://evil.example.com/loggedout"}},
+		{"client_id": {"back"}, "post_logout_redirect_uri": {"http://localhost:14000/loggedout"}},
+		{"client_id": {"unknown"}},
+	}
+
+	for i, form := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/logout", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = form
+		if lr := server.HandleLogoutRequest(resp, req); lr != nil {
+			lr.Authorized = true
+			server.FinishLogoutRequest(resp, req, lr)
+		}
+		if resp.ErrorType != ErrInvalidRequest.Type || resp.ResponseType == REDIRECT {
+			t.Errorf("Expected error %q (%d), got: %q", ErrInvalidRequest.Type, i, resp.ErrorType)
+		}
+	}
+
+	// session is kept
+	if _, ok := storage.Sessions["s1"]; !ok {
+		t.Fatalf("Session should not have been removed")
+	}
+}
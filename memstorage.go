@@ -4,6 +4,7 @@ import (
 	"errors"
 	"log"
 	"sync"
+	"time"
 )
 
 // Logger is a func compatible with most logging func's.
@@ -27,6 +28,27 @@ type MemStorage struct {
 	// RefreshGrants are the saved refresh grants.
 	RefreshGrants map[string]string
 
+	// DeviceAuthorizations are the saved device authorizations.
+	DeviceAuthorizations map[string]*DeviceAuthorization
+
+	// UserCodes are the saved device authorization user codes.
+	UserCodes map[string]string
+
+	// BackchannelAuthentications are the saved backchannel authentications.
+	BackchannelAuthentications map[string]*BackchannelAuthentication
+
+	// Sessions are the saved end-user sessions.
+	Sessions map[string]*Session
+
+	// JTIs are the saved JWT ids and their expiration.
+	JTIs map[string]time.Time
+
+	// PushedAuthorizations are the saved pushed authorizations.
+	PushedAuthorizations map[string]*PushedAuthorization
+
+	// Keys are the saved signing keys.
+	Keys map[string]*Key
+
 	// Logger is a logger to log output to.
 	Logger Logger
 }
@@ -38,6 +60,15 @@ func NewMemStorage() *MemStorage {
 		AuthorizeData: make(map[string]*AuthorizeData),
 		AccessGrants:  make(map[string]*AccessGrant),
 		RefreshGrants: make(map[string]string),
+
+		DeviceAuthorizations: make(map[string]*DeviceAuthorization),
+		UserCodes:            make(map[string]string),
+		JTIs:                 make(map[string]time.Time),
+		PushedAuthorizations: make(map[string]*PushedAuthorization),
+		Keys:                 make(map[string]*Key),
+
+		BackchannelAuthentications: make(map[string]*BackchannelAuthentication),
+		Sessions:                   make(map[string]*Session),
 	}
 }
 
@@ -76,6 +107,17 @@ func (ms *MemStorage) SetClient(id string, client Client) error {
 	return nil
 }
 
+// RemoveClient deletes the Client with id from storage.
+func (ms *MemStorage) RemoveClient(id string) error {
+	ms.printf("RemoveClient: %s\n", id)
+
+	ms.Lock()
+	delete(ms.Clients, id)
+	ms.Unlock()
+
+	return nil
+}
+
 // SaveAuthorizeData saves the provided authorize data.
 func (ms *MemStorage) SaveAuthorizeData(ad *AuthorizeData) error {
 	ms.printf("SaveAuthorizeData: %s\n", ad.Code)
@@ -189,3 +231,228 @@ func (ms *MemStorage) RemoveRefreshGrant(code string) error {
 
 	return nil
 }
+
+// SaveJTI saves the JWT id until expiration. Returns false if the JWT id was
+// previously saved and has not expired.
+func (ms *MemStorage) SaveJTI(id string, expiration time.Time) (bool, error) {
+	ms.printf("SaveJTI: %s\n", id)
+
+	ms.Lock()
+	defer ms.Unlock()
+
+	// remove expired ids
+	now := time.Now()
+	for k, exp := range ms.JTIs {
+		if exp.Before(now) {
+			delete(ms.JTIs, k)
+		}
+	}
+
+	if _, ok := ms.JTIs[id]; ok {
+		return false, nil
+	}
+	ms.JTIs[id] = expiration
+
+	return true, nil
+}
+
+// SaveDeviceAuthorization saves the DeviceAuthorization to storage.
+func (ms *MemStorage) SaveDeviceAuthorization(da *DeviceAuthorization) error {
+	ms.printf("SaveDeviceAuthorization: %s\n", da.DeviceCode)
+
+	ms.Lock()
+	ms.DeviceAuthorizations[da.DeviceCode] = da
+	ms.UserCodes[da.UserCode] = da.DeviceCode
+	ms.Unlock()
+
+	return nil
+}
+
+// LoadDeviceAuthorization retrieves a DeviceAuthorization by device code.
+func (ms *MemStorage) LoadDeviceAuthorization(deviceCode string) (*DeviceAuthorization, error) {
+	ms.printf("LoadDeviceAuthorization: %s\n", deviceCode)
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	if d, ok := ms.DeviceAuthorizations[deviceCode]; ok {
+		return d, nil
+	}
+
+	return nil, errors.New("Device authorization not found")
+}
+
+// LoadDeviceAuthorizationByUserCode retrieves a DeviceAuthorization by user
+// code.
+func (ms *MemStorage) LoadDeviceAuthorizationByUserCode(userCode string) (*DeviceAuthorization, error) {
+	ms.printf("LoadDeviceAuthorizationByUserCode: %s\n", userCode)
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	if d, ok := ms.DeviceAuthorizations[ms.UserCodes[userCode]]; ok {
+		return d, nil
+	}
+
+	return nil, errors.New("Device authorization not found")
+}
+
+// RemoveDeviceAuthorization deletes a DeviceAuthorization.
+func (ms *MemStorage) RemoveDeviceAuthorization(deviceCode string) error {
+	ms.printf("RemoveDeviceAuthorization: %s\n", deviceCode)
+
+	ms.Lock()
+	if d, ok := ms.DeviceAuthorizations[deviceCode]; ok {
+		delete(ms.UserCodes, d.UserCode)
+	}
+	delete(ms.DeviceAuthorizations, deviceCode)
+	ms.Unlock()
+
+	return nil
+}
+
+// SaveBackchannelAuthentication saves the BackchannelAuthentication to
+// storage.
+func (ms *MemStorage) SaveBackchannelAuthentication(ba *BackchannelAuthentication) error {
+	ms.printf("SaveBackchannelAuthentication: %s\n", ba.AuthReqID)
+
+	ms.Lock()
+	ms.BackchannelAuthentications[ba.AuthReqID] = ba
+	ms.Unlock()
+
+	return nil
+}
+
+// LoadBackchannelAuthentication retrieves a BackchannelAuthentication by
+// authentication request id.
+func (ms *MemStorage) LoadBackchannelAuthentication(authReqID string) (*BackchannelAuthentication, error) {
+	ms.printf("LoadBackchannelAuthentication: %s\n", authReqID)
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	if d, ok := ms.BackchannelAuthentications[authReqID]; ok {
+		return d, nil
+	}
+
+	return nil, errors.New("Backchannel authentication not found")
+}
+
+// RemoveBackchannelAuthentication deletes a BackchannelAuthentication.
+func (ms *MemStorage) RemoveBackchannelAuthentication(authReqID string) error {
+	ms.printf("RemoveBackchannelAuthentication: %s\n", authReqID)
+
+	ms.Lock()
+	delete(ms.BackchannelAuthentications, authReqID)
+	ms.Unlock()
+
+	return nil
+}
+
+// SaveSession saves the Session to storage.
+func (ms *MemStorage) SaveSession(session *Session) error {
+	ms.printf("SaveSession: %s\n", session.ID)
+
+	ms.Lock()
+	ms.Sessions[session.ID] = session
+	ms.Unlock()
+
+	return nil
+}
+
+// LoadSession retrieves a Session by id.
+func (ms *MemStorage) LoadSession(id string) (*Session, error) {
+	ms.printf("LoadSession: %s\n", id)
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	if d, ok := ms.Sessions[id]; ok {
+		return d, nil
+	}
+
+	return nil, ErrSessionNotFound
+}
+
+// RemoveSession deletes a Session.
+func (ms *MemStorage) RemoveSession(id string) error {
+	ms.printf("RemoveSession: %s\n", id)
+
+	ms.Lock()
+	delete(ms.Sessions, id)
+	ms.Unlock()
+
+	return nil
+}
+
+// SavePushedAuthorization saves the PushedAuthorization to storage.
+func (ms *MemStorage) SavePushedAuthorization(pa *PushedAuthorization) error {
+	ms.printf("SavePushedAuthorization: %s\n", pa.RequestURI)
+
+	ms.Lock()
+	ms.PushedAuthorizations[pa.RequestURI] = pa
+	ms.Unlock()
+
+	return nil
+}
+
+// LoadPushedAuthorization retrieves a PushedAuthorization by request uri.
+func (ms *MemStorage) LoadPushedAuthorization(requestURI string) (*PushedAuthorization, error) {
+	ms.printf("LoadPushedAuthorization: %s\n", requestURI)
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	if d, ok := ms.PushedAuthorizations[requestURI]; ok {
+		return d, nil
+	}
+
+	return nil, errors.New("Pushed authorization not found")
+}
+
+// RemovePushedAuthorization deletes a PushedAuthorization.
+func (ms *MemStorage) RemovePushedAuthorization(requestURI string) error {
+	ms.printf("RemovePushedAuthorization: %s\n", requestURI)
+
+	ms.Lock()
+	delete(ms.PushedAuthorizations, requestURI)
+	ms.Unlock()
+
+	return nil
+}
+
+// SaveKey saves the Key to storage.
+func (ms *MemStorage) SaveKey(k *Key) error {
+	ms.printf("SaveKey: %s\n", k.JWK.KeyID)
+
+	ms.Lock()
+	ms.Keys[k.JWK.KeyID] = k
+	ms.Unlock()
+
+	return nil
+}
+
+// LoadKeys retrieves all saved keys.
+func (ms *MemStorage) LoadKeys() ([]*Key, error) {
+	ms.printf("LoadKeys\n")
+
+	ms.RLock()
+	defer ms.RUnlock()
+
+	var keys []*Key
+	for _, k := range ms.Keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// RemoveKey deletes a Key.
+func (ms *MemStorage) RemoveKey(kid string) error {
+	ms.printf("RemoveKey: %s\n", kid)
+
+	ms.Lock()
+	delete(ms.Keys, kid)
+	ms.Unlock()
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MetadataPath is the well-known path of the server metadata document.
+//
+// See http://tools.ietf.org/html/rfc8414#section-3
+const MetadataPath = "/.well-known/oauth-authorization-server"
+
+// clientAuthMethods returns the client authentication methods supported by
+// the server.
+func (s *Server) clientAuthMethods() []string {
+	return []string{
+		ClientAuthSecretBasic,
+		ClientAuthSecretPost,
+		ClientAuthSecretJWT,
+		ClientAuthPrivateKeyJWT,
+		ClientAuthNone,
+		ClientAuthTLS,
+		ClientAuthSelfSignedTLS,
+	}
+}
+
+// clientAuthSigningAlgs returns the signing algorithms supported for JWT
+// client authentication.
+func (s *Server) clientAuthSigningAlgs() []string {
+	return []string{
+		AlgHS256, AlgHS384, AlgHS512,
+		AlgRS256, AlgRS384, AlgRS512, AlgPS256,
+		AlgES256, AlgES384, AlgES512,
+		AlgEdDSA,
+	}
+}
+
+// grantTypes returns the grant types supported by the server, as advertised in
+// the server metadata.
+func (s *Server) grantTypes() []string {
+	var ret []string
+	for _, gt := range s.Config.AllowedGrantTypes {
+		if gt == ImplicitGrant {
+			continue
+		}
+		ret = append(ret, gt.String())
+	}
+	// all response types but "code" return tokens from the authorization
+	// endpoint
+	for _, rt := range s.Config.AllowedAuthRequestTypes {
+		if normalizeResponseType(rt) != "code" {
+			ret = append(ret, "implicit")
+			break
+		}
+	}
+	return ret
+}
+
+// Metadata returns the server metadata generated from the server Config.
+//
+// See http://tools.ietf.org/html/rfc8414#section-2
+func (s *Server) Metadata() ResponseData {
+	md := ResponseData{
+		"issuer":                                s.Config.Issuer,
+		"response_types_supported":              s.Config.AllowedAuthRequestTypes,
+		"grant_types_supported":                 s.grantTypes(),
+		"token_endpoint_auth_methods_supported": s.clientAuthMethods(),
+	}
+	md["token_endpoint_auth_signing_alg_values_supported"] = s.clientAuthSigningAlgs()
+	md["tls_client_certificate_bound_access_tokens"] = true
+	md["dpop_signing_alg_values_supported"] = s.dpopSigningAlgs()
+
+	// add endpoints
+	endpoints := []struct {
+		name string
+		url  string
+	}{
+		{"authorization_endpoint", s.Config.Endpoints.Authorization},
+		{"token_endpoint", s.Config.Endpoints.Token},
+		{"revocation_endpoint", s.Config.Endpoints.Revocation},
+		{"introspection_endpoint", s.Config.Endpoints.Introspection},
+		{"registration_endpoint", s.Config.Endpoints.Registration},
+		{"device_authorization_endpoint", s.Config.Endpoints.DeviceAuthorization},
+		{"pushed_authorization_request_endpoint", s.Config.Endpoints.PushedAuthorization},
+		{"userinfo_endpoint", s.Config.Endpoints.UserInfo},
+		{"jwks_uri", s.Config.Endpoints.JWKS},
+		{"backchannel_authentication_endpoint", s.Config.Endpoints.BackchannelAuthentication},
+		{"end_session_endpoint", s.Config.Endpoints.EndSession},
+	}
+	for _, e := range endpoints {
+		if e.url != "" {
+			md[e.name] = e.url
+		}
+	}
+	if s.Config.Endpoints.Revocation != "" {
+		md["revocation_endpoint_auth_methods_supported"] = s.clientAuthMethods()
+	}
+	if s.Config.Endpoints.Introspection != "" {
+		md["introspection_endpoint_auth_methods_supported"] = s.clientAuthMethods()
+	}
+
+	if s.Config.RequirePushedAuthorizationRequests {
+		md["require_pushed_authorization_requests"] = true
+	}
+
+	// request objects
+	md["request_parameter_supported"] = true
+	md["request_uri_parameter_supported"] = s.RequestObjectLoader != nil
+	md["request_object_signing_alg_values_supported"] = s.clientAuthSigningAlgs()
+	if s.Config.RequireSignedRequestObject {
+		md["require_signed_request_object"] = true
+	}
+
+	// openid connect, see:
+	// http://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+	if alg := s.signingAlg(); alg != "" {
+		md["subject_types_supported"] = []string{"public"}
+		md["id_token_signing_alg_values_supported"] = []string{alg}
+		md["userinfo_signing_alg_values_supported"] = []string{alg}
+		md["claims_parameter_supported"] = true
+	}
+
+	// client initiated backchannel authentication, see:
+	// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.4
+	if s.Config.isGrantTypeAllowed(CIBAGrant) {
+		md["backchannel_token_delivery_modes_supported"] = []string{BackchannelModePoll, BackchannelModePing, BackchannelModePush}
+		md["backchannel_user_code_parameter_supported"] = false
+	}
+
+	// logout, see:
+	// http://openid.net/specs/openid-connect-frontchannel-1_0.html#OPMetadata
+	// http://openid.net/specs/openid-connect-backchannel-1_0.html#BCSupport
+	if s.Config.Endpoints.EndSession != "" {
+		md["frontchannel_logout_supported"] = true
+		md["frontchannel_logout_session_supported"] = true
+		md["backchannel_logout_supported"] = true
+		md["backchannel_logout_session_supported"] = true
+	}
+
+	// rich authorization requests, see:
+	// http://tools.ietf.org/html/rfc9396#section-10
+	if len(s.AuthorizationDetailTypes) != 0 {
+		md["authorization_details_types_supported"] = s.authorizationDetailTypes()
+	}
+
+	// response modes, see:
+	// http://openid.net/specs/oauth-v2-jarm.html#name-authorization-server-metada
+	if len(s.Config.AllowedResponseModes) != 0 {
+		md["response_modes_supported"] = s.Config.AllowedResponseModes
+	}
+	if alg := s.signingAlg(); alg != "" {
+		for _, mode := range s.Config.AllowedResponseModes {
+			if isJWTResponseMode(mode) {
+				md["authorization_signing_alg_values_supported"] = []string{alg}
+				break
+			}
+		}
+	}
+
+	if len(s.Config.AllowedCodeChallengeMethods) != 0 {
+		md["code_challenge_methods_supported"] = s.Config.AllowedCodeChallengeMethods
+	}
+	if len(s.Config.Scopes) != 0 {
+		md["scopes_supported"] = s.Config.Scopes
+	}
+
+	return md
+}
+
+// HandleMetadataRequest is the http.HandlerFunc for serving the server
+// metadata document, normally served at MetadataPath on the server.
+func (s *Server) HandleMetadataRequest(w *Response, r *http.Request) {
+	if r.Method != "GET" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be GET")
+		return
+	}
+
+	// issuer is required
+	if s.Config.Issuer == "" {
+		w.SetError(ErrServerError)
+		w.InternalError = errors.New("issuer not configured")
+		return
+	}
+
+	w.Output = s.Metadata()
+}
@@ -0,0 +1,65 @@
+package oauthlib
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.Issuer = "http://localhost:14000"
+	sconfig.Endpoints.Token = "http://localhost:14000/token"
+	sconfig.AllowedAuthRequestTypes = []string{"code", "token"}
+	sconfig.AllowedGrantTypes = []GrantType{AuthorizationCodeGrant, RefreshTokenGrant}
+	server := NewServer(sconfig, NewTestStorage(t))
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000"+MetadataPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.HandleMetadataRequest(resp, req)
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	if d := resp.Output["issuer"]; d != "http://localhost:14000" {
+		t.Fatalf("Unexpected issuer: %s", d)
+	}
+
+	if d := resp.Output["token_endpoint"]; d != "http://localhost:14000/token" {
+		t.Fatalf("Unexpected token endpoint: %s", d)
+	}
+
+	if _, ok := resp.Output["authorization_endpoint"]; ok {
+		t.Fatalf("Unconfigured endpoint should not be returned")
+	}
+
+	gt := []string{"authorization_code", "refresh_token", "implicit"}
+	if d := resp.Output["grant_types_supported"]; !reflect.DeepEqual(d, gt) {
+		t.Fatalf("Unexpected grant types: %v", d)
+	}
+}
+
+func TestMetadataWithoutIssuer(t *testing.T) {
+	server := NewServer(NewConfig(), NewTestStorage(t))
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000"+MetadataPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.HandleMetadataRequest(resp, req)
+
+	if !resp.IsError {
+		t.Fatalf("Metadata without issuer should be an error")
+	}
+}
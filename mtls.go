@@ -0,0 +1,154 @@
+package oauthlib
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// CertificateThumbprint returns the base64url encoded SHA-256 thumbprint of
+// the certificate, as used in the "x5t#S256" confirmation method, see:
+// http://tools.ietf.org/html/rfc8705#section-3.1
+func CertificateThumbprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return b64(hash[:])
+}
+
+// clientCertificate returns the mutual TLS client certificate of the request,
+// or nil if the request was not sent over mutual TLS.
+func clientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// certificateSubjectMatches determines if the certificate matches the
+// tls_client_auth subject registered in the client metadata. Only the first
+// registered subject field is used, see:
+// http://tools.ietf.org/html/rfc8705#section-2.1.2
+func certificateSubjectMatches(md *ClientMetadata, cert *x509.Certificate) bool {
+	switch {
+	case md.TLSClientAuthSubjectDN != "":
+		return cert.Subject.String() == md.TLSClientAuthSubjectDN
+
+	case md.TLSClientAuthSANDNS != "":
+		for _, name := range cert.DNSNames {
+			if name == md.TLSClientAuthSANDNS {
+				return true
+			}
+		}
+
+	case md.TLSClientAuthSANURI != "":
+		for _, u := range cert.URIs {
+			if u.String() == md.TLSClientAuthSANURI {
+				return true
+			}
+		}
+
+	case md.TLSClientAuthSANIP != "":
+		ip := net.ParseIP(md.TLSClientAuthSANIP)
+		for _, addr := range cert.IPAddresses {
+			if addr.Equal(ip) {
+				return true
+			}
+		}
+
+	case md.TLSClientAuthSANEmail != "":
+		for _, email := range cert.EmailAddresses {
+			if email == md.TLSClientAuthSANEmail {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// certificateKeyMatches determines if the public key of the certificate is
+// one of the keys in the key set.
+func certificateKeyMatches(keys *JSONWebKeySet, cert *x509.Certificate) bool {
+	pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if keys == nil || !ok {
+		return false
+	}
+
+	for _, k := range keys.Keys {
+		if pub.Equal(publicKey(k.Key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateClientCertificate authenticates the client using the mutual TLS
+// client certificate of the request. Sets an error on the response if
+// authentication fails.
+func authenticateClientCertificate(w *Response, r *http.Request, client Client, method string) bool {
+	cert := clientCertificate(r)
+	if cert == nil {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client certificate not sent")
+		return false
+	}
+
+	// pki certificates must have been verified by the tls server
+	if method == ClientAuthTLS && len(r.TLS.VerifiedChains) == 0 {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client certificate not verified")
+		return false
+	}
+
+	if c, ok := client.(ClientCertificateMatcher); !ok || !c.ClientCertificateMatches(method, cert) {
+		w.SetError(ErrInvalidClient)
+		w.InternalError = errors.New("client certificate does not match")
+		return false
+	}
+
+	return true
+}
+
+// certificateBinding returns the thumbprint of the client certificate that
+// the tokens issued for the request are bound to, or blank if the tokens are
+// not bound, see:
+// http://tools.ietf.org/html/rfc8705#section-3
+func (s *Server) certificateBinding(client Client, r *http.Request) string {
+	cert := clientCertificate(r)
+	if cert == nil {
+		return ""
+	}
+
+	bound := s.Config.CertificateBoundAccessTokens
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil && md.TLSClientCertificateBoundAccessTokens {
+			bound = true
+		}
+	}
+	if !bound {
+		return ""
+	}
+
+	return CertificateThumbprint(cert)
+}
+
+// checkCertificateBinding verifies that the request was sent over the client
+// certificate the access grant is bound to.
+func checkCertificateBinding(ag *AccessGrant, r *http.Request) error {
+	if ag.CertificateThumbprint == "" {
+		return nil
+	}
+
+	cert := clientCertificate(r)
+	if cert == nil {
+		return errors.New("token is bound to a client certificate")
+	}
+	if subtle.ConstantTimeCompare([]byte(CertificateThumbprint(cert)), []byte(ag.CertificateThumbprint)) != 1 {
+		return errors.New("client certificate does not match token binding")
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newTestCertificate creates a self-signed client certificate for name.
+func newTestCertificate(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	buf, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// withCertificate sets the mutual TLS client certificate of the request.
+func withCertificate(req *http.Request, cert *x509.Certificate, verified bool) {
+	if cert == nil {
+		return
+	}
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+	}
+	if verified {
+		req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+}
+
+func TestAuthenticateClientCertificate(t *testing.T) {
+	pkiCert, _ := newTestCertificate(t, "client.example.com")
+	selfCert, selfKey := newTestCertificate(t, "self.example.com")
+	otherCert, _ := newTestCertificate(t, "other.example.com")
+
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	for _, c := range []*DefaultClient{
+		{ID: "pki", Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthTLS, TLSClientAuthSANDNS: "client.example.com"}},
+		{ID: "self", Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthSelfSignedTLS, JWKS: &JSONWebKeySet{Keys: []JSONWebKey{{Key: &selfKey.PublicKey}}}}},
+		{ID: "thumb", Metadata: ClientMetadata{TokenEndpointAuthMethod: ClientAuthSelfSignedTLS}, CertificateThumbprint: CertificateThumbprint(selfCert)},
+	} {
+		c.RedirectURI = "http://localhost:14000/otherauth"
+		if err := storage.SetClient(c.ID, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		id       string
+		cert     *x509.Certificate
+		verified bool
+		valid    bool
+	}{
+		{"pki", pkiCert, true, true},
+		{"pki", pkiCert, false, false}, // not verified
+		{"pki", otherCert, true, false},
+		{"pki", nil, false, false},
+		{"self", selfCert, false, true},
+		{"self", otherCert, false, false},
+		{"thumb", selfCert, false, true},
+		{"thumb", otherCert, false, false},
+		{"1234", selfCert, false, false}, // client_secret_basic
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withCertificate(req, tt.cert, tt.verified)
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("client_id", tt.id)
+
+		client := server.authenticateClient(resp, req)
+		if tt.valid && (client == nil || client.GetID() != tt.id) {
+			t.Errorf("Expected authentication to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && client != nil {
+			t.Errorf("Expected authentication to fail (%d)", i)
+		}
+	}
+}
+
+func TestCertificateBoundAccessToken(t *testing.T) {
+	cert, _ := newTestCertificate(t, "client.example.com")
+	otherCert, _ := newTestCertificate(t, "other.example.com")
+
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{ClientCredentialsGrant}
+	storage := NewTestStorage(t)
+	err := storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		RedirectURI: "http://localhost:14000/otherauth",
+		Metadata: ClientMetadata{
+			TokenEndpointAuthMethod:               ClientAuthTLS,
+			TLSClientAuthSANDNS:                   "client.example.com",
+			TLSClientCertificateBoundAccessTokens: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	// issue token
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCertificate(req, cert, true)
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(ClientCredentialsGrant))
+	req.Form.Set("client_id", "5678")
+
+	if ar := server.HandleTokenRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishTokenRequest(resp, req, ar)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	ag, err := storage.LoadAccessGrant("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ag.CertificateThumbprint != CertificateThumbprint(cert) {
+		t.Fatalf("Unexpected certificate thumbprint: %s", ag.CertificateThumbprint)
+	}
+
+	// present token
+	var tests = []struct {
+		cert  *x509.Certificate
+		valid bool
+	}{
+		{cert, true},
+		{otherCert, false},
+		{nil, false},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/resource", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withCertificate(req, tt.cert, false)
+		req.Header.Set("Authorization", "Bearer 1")
+
+		rr := server.HandleResourceRequest(resp, req, "http://localhost:14000/resource")
+		if tt.valid && rr == nil {
+			t.Errorf("Expected resource request to succeed (%d), got: %v", i, resp.InternalError)
+		} else if !tt.valid && (rr != nil || resp.StatusCode != http.StatusUnauthorized) {
+			t.Errorf("Expected resource request to fail (%d)", i)
+		}
+	}
+}
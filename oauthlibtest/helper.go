@@ -19,11 +19,13 @@ func HandleLoginPage(ar *oauthlib.AuthRequest, w http.ResponseWriter, r *http.Re
 	w.Write([]byte("<html><body>"))
 	w.Write([]byte(fmt.Sprintf("LOGIN %s (use test/test)<br/>", ar.Client.GetID())))
 	w.Write([]byte(fmt.Sprintf(
-		"<form action=\"/authorize?response_type=%s&client_id=%s&state=%s&redirect_uri=%s\" method=\"POST\">",
+		"<form action=\"/authorize?response_type=%s&client_id=%s&state=%s&redirect_uri=%s&code_challenge=%s&code_challenge_method=%s\" method=\"POST\">",
 		ar.Type,
 		ar.Client.GetID(),
 		ar.State,
 		url.QueryEscape(ar.RedirectURI),
+		url.QueryEscape(ar.CodeChallenge),
+		url.QueryEscape(ar.CodeChallengeMethod),
 	)))
 
 	w.Write([]byte("Login: <input type=\"text\" name=\"login\" /><br/>"))
@@ -0,0 +1,102 @@
+package oauthlib
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+// Code challenge methods, see:
+// http://tools.ietf.org/html/rfc7636#section-4.2
+const (
+	// PKCEMethodPlain is the plain code challenge method.
+	PKCEMethodPlain = "plain"
+
+	// PKCEMethodS256 is the S256 code challenge method.
+	PKCEMethodS256 = "S256"
+)
+
+// PKCERequirement controls when authorization requests must include a PKCE
+// code challenge.
+type PKCERequirement int
+
+const (
+	// PKCEOptional accepts authorization requests with or without a code
+	// challenge.
+	PKCEOptional PKCERequirement = iota
+
+	// PKCERequiredPublic requires a code challenge from public clients only.
+	PKCERequiredPublic
+
+	// PKCERequired requires a code challenge from all clients.
+	PKCERequired
+)
+
+// isPKCERequired determines if the client must send a code challenge.
+func (c Config) isPKCERequired(client Client) bool {
+	switch c.RequirePKCE {
+	case PKCERequired:
+		return true
+	case PKCERequiredPublic:
+		return isPublicClient(client)
+	}
+	return false
+}
+
+// isCodeChallengeMethodAllowed determines if the passed code challenge method
+// is in the Config.AllowedCodeChallengeMethods.
+func (c Config) isCodeChallengeMethodAllowed(method string) bool {
+	for _, k := range c.AllowedCodeChallengeMethods {
+		if k == method {
+			return true
+		}
+	}
+	return false
+}
+
+// validPKCEValue determines if v is a valid code challenge or code verifier,
+// ie, 43 to 128 characters from the unreserved character set.
+//
+// See http://tools.ietf.org/html/rfc7636#section-4.1
+func validPKCEValue(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+
+	for _, c := range v {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// verifyCodeChallenge verifies that the code verifier matches the code
+// challenge using the code challenge method.
+func verifyCodeChallenge(challenge, method, verifier string) error {
+	if !validPKCEValue(verifier) {
+		return errors.New("invalid code verifier")
+	}
+
+	var expected string
+	switch method {
+	case PKCEMethodPlain:
+		expected = verifier
+	case PKCEMethodS256:
+		hash := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(hash[:])
+	default:
+		return errors.New("unknown code challenge method")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) != 1 {
+		return errors.New("code verifier does not match code challenge")
+	}
+
+	return nil
+}
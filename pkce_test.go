@@ -0,0 +1,140 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+const (
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	if err := verifyCodeChallenge(testCodeChallenge, PKCEMethodS256, testCodeVerifier); err != nil {
+		t.Errorf("S256 verification failed: %s", err)
+	}
+
+	if err := verifyCodeChallenge(testCodeVerifier, PKCEMethodPlain, testCodeVerifier); err != nil {
+		t.Errorf("plain verification failed: %s", err)
+	}
+
+	if err := verifyCodeChallenge(testCodeChallenge, PKCEMethodPlain, testCodeVerifier); err == nil {
+		t.Errorf("plain verification should have failed")
+	}
+
+	if err := verifyCodeChallenge(testCodeChallenge, PKCEMethodS256, "short"); err == nil {
+		t.Errorf("verification with invalid verifier should have failed")
+	}
+}
+
+func TestAuthorizeCodePKCE(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedAuthRequestTypes = []string{"code"}
+	sconfig.AllowedGrantTypes = []GrantType{AuthorizationCodeGrant}
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AuthorizeTokenGen = &TestingAuthorizeTokenGen{}
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	// authorize with code challenge
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("response_type", "code")
+	req.Form.Set("client_id", "1234")
+	req.Form.Set("state", "a")
+	req.Form.Set("code_challenge", testCodeChallenge)
+	req.Form.Set("code_challenge_method", PKCEMethodS256)
+
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishAuthRequest(resp, req, ar)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	code, _ := resp.Output["code"].(string)
+
+	// exchange code with wrong verifier
+	for _, v := range []string{"", "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"} {
+		resp = server.NewResponse()
+		req, err = http.NewRequest("POST", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("grant_type", string(AuthorizationCodeGrant))
+		req.Form.Set("code", code)
+		req.Form.Set("code_verifier", v)
+
+		if ar := server.HandleTokenRequest(resp, req); ar != nil {
+			t.Fatalf("Token request with verifier %q should have failed", v)
+		}
+		if resp.ErrorType != ErrInvalidGrant.Type {
+			t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+		}
+	}
+
+	// exchange code with correct verifier
+	resp = server.NewResponse()
+	req, err = http.NewRequest("POST", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(AuthorizationCodeGrant))
+	req.Form.Set("code", code)
+	req.Form.Set("code_verifier", testCodeVerifier)
+
+	if ar := server.HandleTokenRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishTokenRequest(resp, req, ar)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["access_token"]; d != "1" {
+		t.Fatalf("Unexpected access token: %s", d)
+	}
+}
+
+func TestAuthorizeCodePKCERequired(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedAuthRequestTypes = []string{"code"}
+	sconfig.RequirePKCE = PKCERequired
+	server := NewServer(sconfig, NewTestStorage(t))
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("response_type", "code")
+	req.Form.Set("client_id", "1234")
+	req.Form.Set("state", "a")
+
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Auth request without code challenge should have failed")
+	}
+
+	if resp.ErrorType != ErrInvalidRequest.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+
+	if d := resp.Output["state"]; d != "a" {
+		t.Fatalf("Unexpected state: %s", d)
+	}
+}
@@ -0,0 +1,201 @@
+package oauthlib
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+// requestURIPrefix is the prefix of the request_uri of pushed authorization
+// requests, see:
+// http://tools.ietf.org/html/rfc9126#section-2.2
+const requestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+// PushedAuthorization is an authorization request pushed by a client,
+// normally sent to "/par" on the server.
+//
+// See http://tools.ietf.org/html/rfc9126
+type PushedAuthorization struct {
+	// Client is the authenticated client information.
+	Client Client
+
+	// RequestURI is the reference to the request, used as the "request_uri"
+	// of the authorization request.
+	RequestURI string
+
+	// Params are the authorization request parameters.
+	Params url.Values
+
+	// ExpiresIn is the request uri expiration in seconds.
+	ExpiresIn int32
+
+	// CreatedAt is the creation time.
+	CreatedAt time.Time
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+}
+
+// IsExpiredAt is true if the pushed authorization expires at time 't'
+func (d *PushedAuthorization) IsExpiredAt(t time.Time) bool {
+	return d.ExpireAt().Before(t)
+}
+
+// ExpireAt returns the expiration date.
+func (d *PushedAuthorization) ExpireAt() time.Time {
+	return d.CreatedAt.Add(time.Duration(d.ExpiresIn) * time.Second)
+}
+
+// getPushedAuthorizationStorage returns the response storage as
+// PushedAuthorizationStorage. Sets an error on the response if the storage
+// does not support pushed authorization requests.
+func getPushedAuthorizationStorage(w *Response) PushedAuthorizationStorage {
+	ps, ok := w.Storage.(PushedAuthorizationStorage)
+	if !ok {
+		w.SetError(ErrServerError)
+		w.InternalError = errors.New("storage does not support pushed authorization requests")
+		return nil
+	}
+	return ps
+}
+
+// isPushedAuthorizationRequired determines if the client must push its
+// authorization requests.
+func (s *Server) isPushedAuthorizationRequired(client Client) bool {
+	if s.Config.RequirePushedAuthorizationRequests {
+		return true
+	}
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil {
+			return md.RequirePushedAuthorizationRequests
+		}
+	}
+	return false
+}
+
+// HandlePushedAuthRequest is the http.HandlerFunc for handling pushed
+// authorization requests. The request parameters are validated the same way
+// as HandleAuthRequest.
+func (s *Server) HandlePushedAuthRequest(w *Response, r *http.Request) *PushedAuthorization {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	// must have a valid client
+	client := s.authenticateClient(w, r)
+	if client == nil {
+		return nil
+	}
+
+	// "request_uri" must not be pushed, see:
+	// http://tools.ietf.org/html/rfc9126#section-2.1
+	if r.PostForm.Get("request_uri") != "" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request_uri not allowed")
+		return nil
+	}
+
+	// copy authorization request parameters, without client authentication
+	params := url.Values{}
+	for k, v := range r.PostForm {
+		switch k {
+		case "client_secret", "client_assertion", "client_assertion_type":
+		default:
+			params[k] = v
+		}
+	}
+	if params.Get("client_id") != client.GetID() {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("client_id does not match authenticated client")
+		return nil
+	}
+
+	// validate as an authorization request, returning errors directly
+	ar := s.handleAuthParams(w, r, params)
+	w.ResponseType, w.URL = DATA, ""
+	w.setResponseMode("")
+	if ar == nil {
+		return nil
+	}
+
+	return &PushedAuthorization{
+		Client:    client,
+		Params:    params,
+		ExpiresIn: s.Config.PushedAuthorizationExpiration,
+	}
+}
+
+// FinishPushedAuthRequest finalizes the request handled by
+// HandlePushedAuthRequest, saving the pushed authorization to storage.
+func (s *Server) FinishPushedAuthRequest(w *Response, r *http.Request, pa *PushedAuthorization) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	ps := getPushedAuthorizationStorage(w)
+	if ps == nil {
+		return
+	}
+
+	// generate request uri
+	token := uuid.NewRandom()
+	pa.RequestURI = requestURIPrefix + removePadding(base64.URLEncoding.EncodeToString([]byte(token)))
+	pa.CreatedAt = s.Now()
+
+	// save pushed authorization
+	if err := ps.SavePushedAuthorization(pa); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// output data
+	w.StatusCode = http.StatusCreated
+	w.Output["request_uri"] = pa.RequestURI
+	w.Output["expires_in"] = pa.ExpiresIn
+}
+
+// loadPushedAuthorization loads the parameters of the pushed authorization
+// referenced by the "request_uri" parameter of the authorization request.
+// Sets an error on the response if the request uri is invalid.
+func (s *Server) loadPushedAuthorization(w *Response, r *http.Request) url.Values {
+	ps := getPushedAuthorizationStorage(w)
+	if ps == nil {
+		return nil
+	}
+
+	pa, err := ps.LoadPushedAuthorization(r.Form.Get("request_uri"))
+	if err != nil {
+		w.SetError(ErrInvalidRequestURI)
+		w.InternalError = err
+		return nil
+	}
+	if pa == nil || pa.Client == nil || pa.IsExpiredAt(s.Now()) {
+		w.SetError(ErrInvalidRequestURI)
+		w.InternalError = errors.New("request uri expired")
+		return nil
+	}
+
+	// request uri must be from the client
+	if r.Form.Get("client_id") != pa.Client.GetID() {
+		w.SetError(ErrInvalidRequestURI)
+		w.InternalError = errors.New("request uri was pushed by another client")
+		return nil
+	}
+
+	return pa.Params
+}
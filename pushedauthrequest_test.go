@@ -0,0 +1,145 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPushedAuthRequest(t *testing.T) {
+	sconfig := NewConfig()
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AuthorizeTokenGen = &TestingAuthorizeTokenGen{}
+
+	// push authorization request
+	resp := server.NewResponse()
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"1234"},
+		"client_secret":         {"aabbccdd"},
+		"state":                 {"a"},
+		"scope":                 {"read write"},
+		"code_challenge":        {testCodeChallenge},
+		"code_challenge_method": {PKCEMethodS256},
+	}
+	req, err := http.NewRequest("POST", "http://localhost:14000/par", strings.NewReader(params.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if pa := server.HandlePushedAuthRequest(resp, req); pa != nil {
+		server.FinishPushedAuthRequest(resp, req, pa)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Unexpected status code: %d", resp.StatusCode)
+	}
+
+	requestURI, _ := resp.Output["request_uri"].(string)
+	if !strings.HasPrefix(requestURI, requestURIPrefix) {
+		t.Fatalf("Unexpected request uri: %s", requestURI)
+	}
+
+	// authorize using request uri
+	resp = server.NewResponse()
+	req, err = http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("client_id", "1234")
+	req.Form.Set("request_uri", requestURI)
+	req.Form.Set("scope", "admin")
+
+	ar := server.HandleAuthRequest(resp, req)
+	if ar == nil {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if ar.Scope != "read write" || ar.State != "a" || ar.CodeChallenge != testCodeChallenge {
+		t.Fatalf("Unexpected authorization request: %+v", ar)
+	}
+
+	ar.Authorized = true
+	server.FinishAuthRequest(resp, req, ar)
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["code"]; d != "1" {
+		t.Fatalf("Unexpected authorization code: %s", d)
+	}
+
+	// request uri can only be used once
+	resp = server.NewResponse()
+	if ar = server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Request uri should not be reusable")
+	}
+
+	if resp.ErrorType != ErrInvalidRequestURI.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+}
+
+func TestPushedAuthRequestInvalid(t *testing.T) {
+	sconfig := NewConfig()
+	server := NewServer(sconfig, NewTestStorage(t))
+
+	var tests = []struct {
+		params url.Values
+		err    *ResponseError
+	}{
+		{url.Values{"response_type": {"code"}, "client_id": {"1234"}, "client_secret": {"invalid"}}, ErrUnauthorizedClient},
+		{url.Values{"response_type": {"token"}, "client_id": {"1234"}, "client_secret": {"aabbccdd"}}, ErrUnsupportedResponseType},
+		{url.Values{"response_type": {"code"}, "client_id": {"1234"}, "client_secret": {"aabbccdd"}, "request_uri": {"urn:a"}}, ErrInvalidRequest},
+		{url.Values{"response_type": {"code"}, "client_id": {"1234"}, "client_secret": {"aabbccdd"}, "redirect_uri": {"http://invalid"}}, ErrInvalidRequest},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/par", strings.NewReader(tt.params.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if pa := server.HandlePushedAuthRequest(resp, req); pa != nil {
+			t.Errorf("Pushed authorization request should have failed (%d)", i)
+			continue
+		}
+
+		if resp.ErrorType != tt.err.Type || resp.ResponseType != DATA {
+			t.Errorf("Unexpected error type (%d): %s", i, resp.ErrorType)
+		}
+	}
+}
+
+func TestAuthRequestRequiresPushed(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.RequirePushedAuthorizationRequests = true
+	server := NewServer(sconfig, NewTestStorage(t))
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{}
+	req.Form.Set("response_type", "code")
+	req.Form.Set("client_id", "1234")
+
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Authorization request should have failed")
+	}
+
+	if resp.ErrorType != ErrInvalidRequest.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+}
@@ -0,0 +1,321 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ClientCredentialsGen generates client ids, secrets and registration access
+// tokens for registered clients.
+type ClientCredentialsGen interface {
+	GenerateClientCredentials(md *ClientMetadata) (id string, secret string, err error)
+	GenerateRegistrationAccessToken(client Client) (string, error)
+}
+
+// RegistrationRequest is a dynamic client registration request, normally sent
+// to "/register" on the server.
+//
+// See http://tools.ietf.org/html/rfc7591
+type RegistrationRequest struct {
+	// Metadata is the validated client metadata from the request.
+	Metadata ClientMetadata
+
+	// InitialAccessToken is the passed initial access token in the request.
+	// Not validated by the library.
+	InitialAccessToken string
+
+	// Client is the registered client.
+	Client *DefaultClient
+
+	// Authorized toggles if request is authorized
+	Authorized bool
+
+	// Data to be passed to storage. Not used by the library.
+	UserData interface{}
+
+	// HttpRequest *http.Request for special use
+	HttpRequest *http.Request
+}
+
+// isGrantTypeRegistrable determines if the grant type can be registered for a
+// client.
+func (s *Server) isGrantTypeRegistrable(gt string) bool {
+	for _, k := range s.grantTypes() {
+		if k == gt {
+			return true
+		}
+	}
+	return false
+}
+
+// isClientAuthMethodSupported determines if the client authentication method
+// is supported by the server.
+func (s *Server) isClientAuthMethodSupported(method string) bool {
+	for _, k := range s.clientAuthMethods() {
+		if k == method {
+			return true
+		}
+	}
+	return false
+}
+
+// isScopeRegistrable determines if all scopes in scope can be registered for
+// a client.
+func (s *Server) isScopeRegistrable(scope string) bool {
+	if len(s.Config.Scopes) == 0 {
+		return true
+	}
+
+	allowed := make(map[string]bool)
+	for _, k := range s.Config.Scopes {
+		allowed[k] = true
+	}
+	for _, k := range splitScope(scope) {
+		if !allowed[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// isAbsoluteURI determines if uri is an absolute URI without fragment.
+func isAbsoluteURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Fragment == ""
+}
+
+// validateClientMetadata validates the client metadata against the server
+// Config, setting default values for omitted fields.
+func (s *Server) validateClientMetadata(md *ClientMetadata) (*ResponseError, error) {
+	// check redirect uris
+	if len(md.RedirectURIs) == 0 {
+		return ErrInvalidRedirectURI, errors.New("redirect uris required")
+	}
+	if len(md.RedirectURIs) > 1 && s.Config.RedirectURISeparator == "" {
+		return ErrInvalidRedirectURI, errors.New("multiple redirect uris not allowed")
+	}
+	for _, uri := range md.RedirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return ErrInvalidRedirectURI, err
+		}
+		if u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			return ErrInvalidRedirectURI, errors.New("redirect uri must be absolute and must not include fragment")
+		}
+		if s.Config.RedirectURISeparator != "" && strings.Contains(uri, s.Config.RedirectURISeparator) {
+			return ErrInvalidRedirectURI, errors.New("redirect uri must not include separator")
+		}
+	}
+
+	// check grant types
+	if len(md.GrantTypes) == 0 {
+		md.GrantTypes = []string{AuthorizationCodeGrant.String()}
+	}
+	for _, gt := range md.GrantTypes {
+		if !s.isGrantTypeRegistrable(gt) {
+			return ErrInvalidClientMetadata, errors.New("grant type not allowed: " + gt)
+		}
+	}
+
+	// check response types
+	if len(md.ResponseTypes) == 0 {
+		md.ResponseTypes = []string{"code"}
+	}
+	for _, rt := range md.ResponseTypes {
+		if !s.Config.isAuthRequestTypeAllowed(rt) {
+			return ErrInvalidClientMetadata, errors.New("response type not allowed: " + rt)
+		}
+	}
+
+	// check client authentication method
+	if md.TokenEndpointAuthMethod == "" {
+		md.TokenEndpointAuthMethod = ClientAuthSecretBasic
+	}
+	if !s.isClientAuthMethodSupported(md.TokenEndpointAuthMethod) {
+		return ErrInvalidClientMetadata, errors.New("token endpoint auth method not supported: " + md.TokenEndpointAuthMethod)
+	}
+	switch md.TokenEndpointAuthMethod {
+	case ClientAuthPrivateKeyJWT, ClientAuthSelfSignedTLS:
+		if md.JWKS == nil || len(md.JWKS.Keys) == 0 {
+			return ErrInvalidClientMetadata, errors.New("jwks required for " + md.TokenEndpointAuthMethod)
+		}
+	case ClientAuthTLS:
+		if md.TLSClientAuthSubjectDN == "" && md.TLSClientAuthSANDNS == "" && md.TLSClientAuthSANURI == "" &&
+			md.TLSClientAuthSANIP == "" && md.TLSClientAuthSANEmail == "" {
+			return ErrInvalidClientMetadata, errors.New("certificate subject required for tls_client_auth")
+		}
+	}
+	if md.TokenEndpointAuthMethod == ClientAuthNone {
+		for _, gt := range md.GrantTypes {
+			if gt == ClientCredentialsGrant.String() {
+				return ErrInvalidClientMetadata, errors.New("public clients cannot use the client credentials grant")
+			}
+		}
+	}
+
+	// check userinfo signing algorithm
+	if md.UserInfoSignedResponseAlg != "" && md.UserInfoSignedResponseAlg != s.signingAlg() {
+		return ErrInvalidClientMetadata, errors.New("userinfo signing alg not supported: " + md.UserInfoSignedResponseAlg)
+	}
+
+	// check backchannel token delivery, see:
+	// http://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.4
+	for _, gt := range md.GrantTypes {
+		if gt != CIBAGrant.String() {
+			continue
+		}
+		switch md.BackchannelTokenDeliveryMode {
+		case BackchannelModePoll:
+		case BackchannelModePing, BackchannelModePush:
+			u, err := url.Parse(md.BackchannelClientNotificationEndpoint)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return ErrInvalidClientMetadata, errors.New("https client notification endpoint required")
+			}
+		default:
+			return ErrInvalidClientMetadata, errors.New("invalid backchannel token delivery mode: " + md.BackchannelTokenDeliveryMode)
+		}
+	}
+
+	// check logout uris
+	for _, uri := range md.PostLogoutRedirectURIs {
+		if !isAbsoluteURI(uri) {
+			return ErrInvalidClientMetadata, errors.New("post logout redirect uri must be absolute and must not include fragment")
+		}
+	}
+	if md.FrontchannelLogoutURI != "" && !isAbsoluteURI(md.FrontchannelLogoutURI) {
+		return ErrInvalidClientMetadata, errors.New("frontchannel logout uri must be absolute and must not include fragment")
+	}
+	if md.BackchannelLogoutURI != "" && !isAbsoluteURI(md.BackchannelLogoutURI) {
+		return ErrInvalidClientMetadata, errors.New("backchannel logout uri must be absolute and must not include fragment")
+	}
+
+	// check scope
+	if !s.isScopeRegistrable(md.Scope) {
+		return ErrInvalidClientMetadata, errors.New("scope not allowed: " + md.Scope)
+	}
+
+	return nil, nil
+}
+
+// HandleRegistrationRequest is the http.HandlerFunc for handling client
+// registration requests.
+func (s *Server) HandleRegistrationRequest(w *Response, r *http.Request) *RegistrationRequest {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	ret := &RegistrationRequest{
+		HttpRequest: r,
+	}
+
+	// get initial access token
+	if bearer := s.checkBearerAuth(r); bearer != nil {
+		ret.InitialAccessToken = bearer.Code
+	}
+	if ret.InitialAccessToken == "" && s.Config.RequireInitialAccessToken {
+		w.SetError(ErrInvalidToken)
+		w.InternalError = errors.New("initial access token required")
+		return nil
+	}
+
+	// decode metadata
+	if err := json.NewDecoder(r.Body).Decode(&ret.Metadata); err != nil {
+		w.SetError(ErrInvalidClientMetadata)
+		w.InternalError = err
+		return nil
+	}
+
+	// validate metadata
+	if e, err := s.validateClientMetadata(&ret.Metadata); e != nil {
+		w.SetError(e)
+		w.InternalError = err
+		return nil
+	}
+
+	return ret
+}
+
+// FinishRegistrationRequest finalizes the request handled by
+// HandleRegistrationRequest, saving the client to storage.
+func (s *Server) FinishRegistrationRequest(w *Response, r *http.Request, rr *RegistrationRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	if !rr.Authorized {
+		w.SetError(ErrAccessDenied)
+		return
+	}
+
+	// generate client credentials
+	id, secret, err := s.ClientCredentialsGen.GenerateClientCredentials(&rr.Metadata)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// public clients have no secret
+	if rr.Metadata.TokenEndpointAuthMethod == ClientAuthNone {
+		secret = ""
+	}
+
+	rr.Client = &DefaultClient{
+		ID:          id,
+		Secret:      secret,
+		RedirectURI: strings.Join(rr.Metadata.RedirectURIs, s.Config.RedirectURISeparator),
+		UserData:    rr.UserData,
+		Metadata:    rr.Metadata,
+	}
+
+	// generate registration access token
+	rr.Client.RegistrationAccessToken, err = s.ClientCredentialsGen.GenerateRegistrationAccessToken(rr.Client)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// save client
+	if err = w.Storage.SetClient(rr.Client.ID, rr.Client); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	// output data
+	w.StatusCode = http.StatusCreated
+	w.Output = s.clientInformation(rr.Client, &rr.Metadata)
+	w.Output["client_id_issued_at"] = s.Now().Unix()
+}
+
+// clientInformation builds the client information response for the client and
+// its metadata.
+//
+// See http://tools.ietf.org/html/rfc7591#section-3.2.1
+func (s *Server) clientInformation(client Client, md *ClientMetadata) ResponseData {
+	ret := ResponseData{}
+
+	// round trip the metadata to get its json fields
+	buf, _ := json.Marshal(md)
+	_ = json.Unmarshal(buf, &ret)
+
+	ret["client_id"] = client.GetID()
+	if c, ok := client.(*DefaultClient); ok && c.RegistrationAccessToken != "" {
+		if c.Secret != "" {
+			ret["client_secret"] = c.Secret
+			ret["client_secret_expires_at"] = 0
+		}
+		ret["registration_access_token"] = c.RegistrationAccessToken
+		if uri := s.registrationClientURI(client); uri != "" {
+			ret["registration_client_uri"] = uri
+		}
+	}
+	return ret
+}
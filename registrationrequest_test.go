@@ -0,0 +1,167 @@
+package oauthlib
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Predictable testing client credentials generation
+type TestingClientCredentialsGen struct {
+	counter int64
+}
+
+func (a *TestingClientCredentialsGen) GenerateClientCredentials(md *ClientMetadata) (id string, secret string, err error) {
+	a.counter++
+	return "client" + strconv.FormatInt(a.counter, 10), "secret", nil
+}
+
+func (a *TestingClientCredentialsGen) GenerateRegistrationAccessToken(client Client) (string, error) {
+	return "reg" + client.GetID(), nil
+}
+
+func TestRegistration(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.RequireInitialAccessToken = true
+	storage := NewTestStorage(t)
+	server := NewServer(sconfig, storage)
+	server.ClientCredentialsGen = &TestingClientCredentialsGen{}
+	resp := server.NewResponse()
+
+	body := `{"redirect_uris":["http://localhost:14000/newapp"],"client_name":"New App"}`
+	req, err := http.NewRequest("POST", "http://localhost:14000/register", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer initial")
+
+	if rr := server.HandleRegistrationRequest(resp, req); rr != nil {
+		rr.Authorized = rr.InitialAccessToken == "initial"
+		server.FinishRegistrationRequest(resp, req, rr)
+	}
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Unexpected status code: %d", resp.StatusCode)
+	}
+
+	if d := resp.Output["client_id"]; d != "client1" {
+		t.Fatalf("Unexpected client id: %s", d)
+	}
+
+	if d := resp.Output["token_endpoint_auth_method"]; d != ClientAuthSecretBasic {
+		t.Fatalf("Unexpected token endpoint auth method: %s", d)
+	}
+
+	client, err := storage.GetClient("client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.GetRedirectURI() != "http://localhost:14000/newapp" {
+		t.Fatalf("Unexpected redirect uri: %s", client.GetRedirectURI())
+	}
+}
+
+func TestRegistrationPublicClient(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	server := NewServer(sconfig, storage)
+	server.ClientCredentialsGen = &TestingClientCredentialsGen{}
+	resp := server.NewResponse()
+
+	body := `{"redirect_uris":["http://localhost:14000/newapp"],"token_endpoint_auth_method":"none"}`
+	req, err := http.NewRequest("POST", "http://localhost:14000/register", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := server.HandleRegistrationRequest(resp, req); rr != nil {
+		rr.Authorized = true
+		server.FinishRegistrationRequest(resp, req, rr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d, ok := resp.Output["client_secret"]; ok {
+		t.Fatalf("Public client should not have a secret: %s", d)
+	}
+
+	client, err := storage.GetClient("client1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isPublicClient(client) {
+		t.Fatalf("Client should be public")
+	}
+}
+
+func TestRegistrationInvalidMetadata(t *testing.T) {
+	sconfig := NewConfig()
+	server := NewServer(sconfig, NewTestStorage(t))
+
+	var tests = []struct {
+		body string
+		err  *ResponseError
+	}{
+		{`{}`, ErrInvalidRedirectURI},
+		{`{"redirect_uris":["/relative"]}`, ErrInvalidRedirectURI},
+		{`{"redirect_uris":["http://a/1","http://a/2"]}`, ErrInvalidRedirectURI},
+		{`{"redirect_uris":["http://a/1"],"grant_types":["password"]}`, ErrInvalidClientMetadata},
+		{`{"redirect_uris":["http://a/1"],"response_types":["token"]}`, ErrInvalidClientMetadata},
+		{`{"redirect_uris":["http://a/1"],"token_endpoint_auth_method":"unknown"}`, ErrInvalidClientMetadata},
+		{`{"redirect_uris":["http://a/1"],"post_logout_redirect_uris":["/relative"]}`, ErrInvalidClientMetadata},
+		{`{"redirect_uris":["http://a/1"],"frontchannel_logout_uri":"http://a/logout#f"}`, ErrInvalidClientMetadata},
+		{`{"redirect_uris":["http://a/1"],"backchannel_logout_uri":"/logout"}`, ErrInvalidClientMetadata},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/register", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rr := server.HandleRegistrationRequest(resp, req); rr != nil {
+			t.Errorf("Registration should have failed (%d) for %s", i, tt.body)
+			continue
+		}
+
+		if resp.ErrorType != tt.err.Type {
+			t.Errorf("Unexpected error type (%d) for %s: %s", i, tt.body, resp.ErrorType)
+		}
+	}
+}
+
+func TestRegistrationRequiresInitialAccessToken(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.RequireInitialAccessToken = true
+	server := NewServer(sconfig, NewTestStorage(t))
+	resp := server.NewResponse()
+
+	body := `{"redirect_uris":["http://localhost:14000/newapp"]}`
+	req, err := http.NewRequest("POST", "http://localhost:14000/register", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rr := server.HandleRegistrationRequest(resp, req); rr != nil {
+		t.Fatalf("Registration without initial access token should have failed")
+	}
+
+	if resp.ErrorType != ErrInvalidToken.Type {
+		t.Fatalf("Unexpected error type: %s", resp.ErrorType)
+	}
+}
@@ -0,0 +1,111 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// isResourceKnown determines if the resource is in the Config.Resources. All
+// resources are known if Config.Resources is blank.
+func (c Config) isResourceKnown(resource string) bool {
+	if len(c.Resources) == 0 {
+		return true
+	}
+	for _, k := range c.Resources {
+		if k == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// validateResources validates the requested resource indicators, which must
+// be absolute URIs without a fragment of known resource servers, see:
+// http://tools.ietf.org/html/rfc8707#section-2
+func (s *Server) validateResources(resources []string) error {
+	for _, resource := range resources {
+		u, err := url.Parse(resource)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() || u.Fragment != "" {
+			return errors.New("resource must be an absolute uri without fragment: " + resource)
+		}
+		if !s.Config.isResourceKnown(resource) {
+			return errors.New("unknown resource: " + resource)
+		}
+	}
+	return nil
+}
+
+// containsAll determines if all of b are in a.
+func containsAll(a, b []string) bool {
+	m := make(map[string]bool, len(a))
+	for _, v := range a {
+		m[v] = true
+	}
+	for _, v := range b {
+		if !m[v] {
+			return false
+		}
+	}
+	return true
+}
+
+// grantedResources returns the resources the grant of the token request is
+// restricted to, those of the refreshed grant or of the authorization. Blank
+// if the grant is not restricted.
+func (ar *TokenRequest) grantedResources() []string {
+	switch {
+	case ar.AccessGrant != nil:
+		return ar.AccessGrant.Resource
+	case ar.AuthorizeData != nil:
+		return ar.AuthorizeData.Audience
+	}
+	return nil
+}
+
+// handleResources restricts the audience of the token to the requested
+// resources, which must be within the resources of the grant. The token is
+// restricted to all resources of the grant if no resource is requested, see:
+// http://tools.ietf.org/html/rfc8707#section-2.2
+func (s *Server) handleResources(w *Response, r *http.Request, ret *TokenRequest) bool {
+	resources := r.Form["resource"]
+	if err := s.validateResources(resources); err != nil {
+		w.SetError(ErrInvalidTarget)
+		w.InternalError = err
+		return false
+	}
+
+	granted := ret.grantedResources()
+	if len(granted) != 0 && !containsAll(granted, resources) {
+		w.SetError(ErrInvalidTarget)
+		w.InternalError = errors.New("the requested resource must not include any resource not originally granted")
+		return false
+	}
+
+	ret.Resource = resources
+	if len(ret.Audience) == 0 {
+		ret.Audience = resources
+		if len(ret.Audience) == 0 {
+			ret.Audience = granted
+		}
+	}
+	return true
+}
+
+// HasAudience determines if the token can be used at the resource server
+// identified by resource. Tokens without an audience can be used at all
+// resource servers.
+func (d *AccessGrant) HasAudience(resource string) bool {
+	if len(d.Audience) == 0 {
+		return true
+	}
+	for _, aud := range d.Audience {
+		if aud == resource {
+			return true
+		}
+	}
+	return false
+}
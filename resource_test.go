@@ -0,0 +1,130 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const (
+	testResourceA = "https://a.example.com/api"
+	testResourceB = "https://b.example.com/api"
+)
+
+func TestResourceIndicators(t *testing.T) {
+	server := newTestServer(t, AuthorizationCodeGrant, RefreshTokenGrant)
+	server.Config.Resources = []string{testResourceA, testResourceB}
+	storage := server.Storage.(*MemStorage)
+
+	// authorize both resources
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"1234"},
+		"resource":      {testResourceA, testResourceB},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	code := resp.Output["code"].(string)
+	if ad := storage.AuthorizeData[code]; !reflect.DeepEqual(ad.Audience, []string{testResourceA, testResourceB}) {
+		t.Fatalf("Unexpected authorization audience: %v", ad.Audience)
+	}
+
+	// restrict token to resource a
+	resp = doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type": {string(AuthorizationCodeGrant)},
+		"code":       {code},
+		"resource":   {testResourceA},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	ag := storage.AccessGrants[resp.Output["access_token"].(string)]
+	if !reflect.DeepEqual(ag.Audience, []string{testResourceA}) || !reflect.DeepEqual(ag.Resource, []string{testResourceA, testResourceB}) {
+		t.Fatalf("Unexpected audience %v and resources %v", ag.Audience, ag.Resource)
+	}
+	if !ag.HasAudience(testResourceA) || ag.HasAudience(testResourceB) {
+		t.Fatalf("Token should only be valid for resource a")
+	}
+
+	// refresh for resource b
+	resp = doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type":    {string(RefreshTokenGrant)},
+		"refresh_token": {ag.RefreshToken},
+		"resource":      {testResourceB},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	ag = storage.AccessGrants[resp.Output["access_token"].(string)]
+	if !reflect.DeepEqual(ag.Audience, []string{testResourceB}) || !reflect.DeepEqual(ag.Resource, []string{testResourceA, testResourceB}) {
+		t.Fatalf("Unexpected audience %v and resources %v", ag.Audience, ag.Resource)
+	}
+
+	// refresh without resource is for all granted resources
+	resp = doTestTokenRequest(t, server, "1234", url.Values{
+		"grant_type":    {string(RefreshTokenGrant)},
+		"refresh_token": {ag.RefreshToken},
+	})
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+	ag = storage.AccessGrants[resp.Output["access_token"].(string)]
+	if !reflect.DeepEqual(ag.Audience, []string{testResourceA, testResourceB}) {
+		t.Fatalf("Unexpected audience: %v", ag.Audience)
+	}
+}
+
+func TestResourceIndicatorsInvalid(t *testing.T) {
+	server := newTestServer(t, AuthorizationCodeGrant)
+	server.Config.Resources = []string{testResourceA, testResourceB}
+	server.Storage.(*MemStorage).AuthorizeData["9999"].Audience = []string{testResourceA}
+
+	var tests = [][]string{
+		{"https://unknown.example.com"},
+		{"/relative"},
+		{testResourceA + "#fragment"},
+		{testResourceB},
+		{testResourceA, testResourceB},
+	}
+
+	for i, resources := range tests {
+		resp := doTestTokenRequest(t, server, "1234", url.Values{
+			"grant_type": {string(AuthorizationCodeGrant)},
+			"code":       {"9999"},
+			"resource":   resources,
+		})
+		if resp.ErrorType != ErrInvalidTarget.Type {
+			t.Errorf("Expected error %q (%d), got: %q", ErrInvalidTarget.Type, i, resp.ErrorType)
+		}
+	}
+
+	// unknown resource at authorization endpoint
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/authorize", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"client_id":     {"1234"},
+		"state":         {"a"},
+		"resource":      {"https://unknown.example.com"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Authorization request should have failed")
+	}
+	if resp.ErrorType != ErrInvalidTarget.Type || resp.ResponseType != REDIRECT || resp.Output["state"] != "a" {
+		t.Fatalf("Expected redirect with error %q, got: %q", ErrInvalidTarget.Type, resp.ErrorType)
+	}
+}
@@ -0,0 +1,169 @@
+package oauthlib
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ResourceRequest is a request to a protected resource, authorized by an
+// access token issued by the server.
+//
+// See http://tools.ietf.org/html/rfc6750
+type ResourceRequest struct {
+	// Token is the access token sent in the request.
+	Token string
+
+	// AccessGrant is the AccessGrant associated with Token.
+	AccessGrant *AccessGrant
+}
+
+// setResourceError sets the error on the response to a protected resource
+// request, including the WWW-Authenticate challenge for the authentication
+// scheme. Insufficient scope errors are forbidden, other errors unauthorized,
+// see:
+// http://tools.ietf.org/html/rfc6750#section-3
+// http://tools.ietf.org/html/rfc9449#section-7.1
+func (s *Server) setResourceError(w *Response, scheme string, e *ResponseError, err error) {
+	w.SetError(e)
+	w.StatusCode = http.StatusUnauthorized
+	if e == ErrInsufficientScope {
+		w.StatusCode = http.StatusForbidden
+	}
+	w.InternalError = err
+
+	challenge := fmt.Sprintf("%s error=%q, error_description=%q", scheme, e.Type, e.Desc)
+	if scheme == DPoPTokenType {
+		challenge += fmt.Sprintf(", algs=%q", strings.Join(s.dpopSigningAlgs(), " "))
+	}
+	w.Headers.Set("WWW-Authenticate", challenge)
+}
+
+// HandleResourceRequest validates the access token of a request to the
+// protected resource identified by resource. The token must have the resource
+// in its audience (see AccessGrant.HasAudience). Tokens bound to a client
+// certificate must be presented over that certificate, and tokens bound to a
+// DPoP key must be presented with the DPoP authentication scheme and a proof
+// of that key.
+//
+// The access token is only read from the Authorization header, see:
+// http://tools.ietf.org/html/rfc6750#section-2.1
+//
+// DPoP proofs are checked against the URL of the request as received by the
+// server, which must be the URL used by the client.
+func (s *Server) HandleResourceRequest(w *Response, r *http.Request, resource string) *ResourceRequest {
+	ss := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(ss) != 2 || ss[1] == "" || (!strings.EqualFold(ss[0], "Bearer") && !strings.EqualFold(ss[0], DPoPTokenType)) {
+		// requests without authentication get no error code, see:
+		// http://tools.ietf.org/html/rfc6750#section-3.1
+		w.SetError(ErrInvalidToken)
+		w.StatusCode = http.StatusUnauthorized
+		w.InternalError = errors.New("access token not sent")
+		w.Headers.Set("WWW-Authenticate", "Bearer")
+		return nil
+	}
+
+	ret := &ResourceRequest{
+		Token: ss[1],
+	}
+	scheme := "Bearer"
+	if strings.EqualFold(ss[0], DPoPTokenType) {
+		scheme = DPoPTokenType
+	}
+
+	if ret.AccessGrant = s.loadResourceGrant(w, r, ret.Token, scheme); ret.AccessGrant == nil {
+		return nil
+	}
+
+	// must be intended for the resource, see:
+	// http://tools.ietf.org/html/rfc8707#section-2
+	if !ret.AccessGrant.HasAudience(resource) {
+		s.setResourceError(w, scheme, ErrInvalidToken, errors.New("access token audience does not include the resource: "+resource))
+		return nil
+	}
+
+	return ret
+}
+
+// loadResourceGrant loads the AccessGrant of the access token sent in a
+// request to a protected resource with the authentication scheme. Sets the
+// error on the response, with the WWW-Authenticate challenge, if the token is
+// invalid or not sent over its certificate or DPoP key binding.
+func (s *Server) loadResourceGrant(w *Response, r *http.Request, token, scheme string) *AccessGrant {
+	// must be a valid access token
+	ag, err := w.Storage.LoadAccessGrant(token)
+	if err != nil {
+		s.setResourceError(w, scheme, ErrInvalidToken, err)
+		return nil
+	}
+	if ag == nil || ag.Client == nil {
+		s.setResourceError(w, scheme, ErrInvalidToken, errors.New("access token not found"))
+		return nil
+	}
+	if ag.IsExpiredAt(s.Now()) {
+		s.setResourceError(w, scheme, ErrInvalidToken, errors.New("access token expired"))
+		return nil
+	}
+
+	// must be sent over the bound certificate, see:
+	// http://tools.ietf.org/html/rfc8705#section-3
+	if err = checkCertificateBinding(ag, r); err != nil {
+		s.setResourceError(w, scheme, ErrInvalidToken, err)
+		return nil
+	}
+
+	// must be sent with a proof of the bound dpop key, see:
+	// http://tools.ietf.org/html/rfc9449#section-7
+	if ag.DPoPThumbprint != "" || scheme == DPoPTokenType {
+		if ag.DPoPThumbprint == "" || scheme != DPoPTokenType {
+			s.setResourceError(w, DPoPTokenType, ErrInvalidToken, errors.New("dpop scheme must be used with dpop bound tokens only"))
+			return nil
+		}
+
+		jkt, e, err := s.verifyDPoPProof(w.Storage, r, requestURL(r), token)
+		if e != nil {
+			s.setResourceError(w, DPoPTokenType, e, err)
+			if e == ErrUseDPoPNonce {
+				s.setDPoPNonce(w)
+			}
+			return nil
+		}
+		if subtle.ConstantTimeCompare([]byte(jkt), []byte(ag.DPoPThumbprint)) != 1 {
+			s.setResourceError(w, DPoPTokenType, ErrInvalidToken, errors.New("dpop proof key does not match token binding"))
+			return nil
+		}
+	}
+
+	return ag
+}
+
+// resourceRequestKey is the context key for the ResourceRequest.
+type resourceRequestKey struct{}
+
+// ResourceRequestFromContext returns the ResourceRequest validated by
+// ResourceHandler.
+func ResourceRequestFromContext(ctx context.Context) *ResourceRequest {
+	rr, _ := ctx.Value(resourceRequestKey{}).(*ResourceRequest)
+	return rr
+}
+
+// ResourceHandler wraps the handler h of the protected resource identified by
+// resource, only passing requests with a valid access token for the resource
+// (see HandleResourceRequest). The ResourceRequest is available to h using
+// ResourceRequestFromContext.
+func (s *Server) ResourceHandler(resource string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := s.NewResponse()
+
+		rr := s.HandleResourceRequest(resp, r, resource)
+		if rr == nil {
+			WriteJSON(w, resp)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), resourceRequestKey{}, rr)))
+	})
+}
@@ -0,0 +1,65 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResourceHandler(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      storage.Clients["1234"],
+		AccessToken: "restricted",
+		ExpiresIn:   3600,
+		Audience:    []string{testResourceA},
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		resource string
+		auth     string
+		status   int
+	}{
+		{testResourceA, "Bearer 9999", http.StatusNoContent},
+		{testResourceA, "Bearer restricted", http.StatusNoContent},
+		{testResourceB, "Bearer restricted", http.StatusUnauthorized},
+		{testResourceA, "Bearer invalid", http.StatusUnauthorized},
+		{testResourceA, "Basic MTIzNDphYWJiY2NkZA==", http.StatusUnauthorized},
+		{testResourceA, "", http.StatusUnauthorized},
+	}
+
+	for i, tt := range tests {
+		h := server.ResourceHandler(tt.resource, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rr := ResourceRequestFromContext(r.Context())
+			if rr == nil || rr.AccessGrant.Client.GetID() != "1234" {
+				t.Errorf("Unexpected resource request: %v", rr)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		req, err := http.NewRequest("GET", tt.resource, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Unexpected status code (%d): %d", i, w.Code)
+		}
+		if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Expected WWW-Authenticate header (%d)", i)
+		}
+	}
+}
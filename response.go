@@ -19,6 +19,9 @@ const (
 
 	// REDIRECT response type.
 	REDIRECT
+
+	// JWT response type, the Body is a signed JWT.
+	JWT
 )
 
 // Response is a server response.
@@ -35,6 +38,18 @@ type Response struct {
 	InternalError      error
 	RedirectInFragment bool
 
+	// ResponseMode is the response_mode of authorization responses. Redirect
+	// responses in the "form_post" modes are written as a HTML form posting
+	// the output to the URL.
+	ResponseMode string
+
+	// Body is the signed JWT of JWT responses
+	Body string
+
+	// FrontchannelLogoutURIs are the front-channel logout URIs of logout
+	// responses, written as iframes of a HTML page.
+	FrontchannelLogoutURIs []string
+
 	// Storage to use in this response - required
 	Storage Storage
 }
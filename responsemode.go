@@ -0,0 +1,122 @@
+package oauthlib
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Authorization response modes, see:
+// http://openid.net/specs/oauth-v2-multiple-response-types-1_0.html#ResponseModes
+// http://openid.net/specs/oauth-v2-form-post-response-mode-1_0.html
+// http://openid.net/specs/oauth-v2-jarm.html#name-response-mode-jwt
+const (
+	ResponseModeQuery       = "query"
+	ResponseModeFragment    = "fragment"
+	ResponseModeFormPost    = "form_post"
+	ResponseModeJWT         = "jwt"
+	ResponseModeQueryJWT    = "query.jwt"
+	ResponseModeFragmentJWT = "fragment.jwt"
+	ResponseModeFormPostJWT = "form_post.jwt"
+)
+
+// defaultResponseMode returns the default response mode of the response
+// type: "query" for "code", and "fragment" for all other response types.
+func defaultResponseMode(responseType string) string {
+	if responseType == "" || responseType == "code" {
+		return ResponseModeQuery
+	}
+	return ResponseModeFragment
+}
+
+// isJWTResponseMode determines if the response mode is one of the JWT
+// Secured Authorization Response Mode (JARM) modes.
+func isJWTResponseMode(mode string) bool {
+	return strings.HasSuffix(mode, "."+ResponseModeJWT)
+}
+
+// isFormPostResponseMode determines if the response mode posts the response
+// parameters in a HTML form.
+func isFormPostResponseMode(mode string) bool {
+	return mode == ResponseModeFormPost || mode == ResponseModeFormPostJWT
+}
+
+// isFragmentResponseMode determines if the response mode returns the
+// response parameters in the redirect uri fragment.
+func isFragmentResponseMode(mode string) bool {
+	return mode == ResponseModeFragment || mode == ResponseModeFragmentJWT
+}
+
+// isResponseModeAllowed determines if the passed response mode is in the
+// Config.AllowedResponseModes.
+func (c Config) isResponseModeAllowed(mode string) bool {
+	for _, k := range c.AllowedResponseModes {
+		if k == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// responseMode resolves the requested response mode of the response type.
+// The mode defaults to the default mode of the response type, and "jwt" is
+// resolved to "query.jwt" or "fragment.jwt".
+func (c Config) responseMode(mode, responseType string) (string, error) {
+	switch mode {
+	case "":
+		mode = defaultResponseMode(responseType)
+	case ResponseModeJWT:
+		mode = defaultResponseMode(responseType) + "." + ResponseModeJWT
+	}
+	if !c.isResponseModeAllowed(mode) {
+		return "", errors.New("response mode not allowed: " + mode)
+	}
+
+	// tokens must not be returned in the query, see:
+	// http://openid.net/specs/oauth-v2-multiple-response-types-1_0.html#Combinations
+	if (mode == ResponseModeQuery || mode == ResponseModeQueryJWT) && defaultResponseMode(responseType) != ResponseModeQuery {
+		return "", errors.New("response mode query not allowed for response type: " + responseType)
+	}
+	return mode, nil
+}
+
+// setResponseMode sets the response mode of the authorization response.
+func (r *Response) setResponseMode(mode string) {
+	r.ResponseMode = mode
+	r.RedirectInFragment = isFragmentResponseMode(mode)
+}
+
+// secureAuthResponse replaces the parameters of authorization responses in
+// the JWT response modes with the "response" parameter, a JWT signed by the
+// server containing the parameters, see:
+// http://openid.net/specs/oauth-v2-jarm.html#name-jwt-based-response-mode
+func (s *Server) secureAuthResponse(w *Response, client Client) {
+	if w.ResponseType != REDIRECT || !isJWTResponseMode(w.ResponseMode) {
+		return
+	}
+
+	key, err := s.signingKey()
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+
+	now := s.Now()
+	claims := JWTClaims{
+		"iss": s.Config.Issuer,
+		"aud": client.GetID(),
+		"exp": now.Add(time.Duration(s.Config.JWTResponseExpiration) * time.Second).Unix(),
+	}
+	for k, v := range w.Output {
+		claims[k] = v
+	}
+
+	token, err := signClaims(key, claims)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+	w.Output = ResponseData{"response": token}
+}
@@ -0,0 +1,252 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testResponseModes are all the response modes.
+var testResponseModes = []string{
+	ResponseModeQuery,
+	ResponseModeFragment,
+	ResponseModeFormPost,
+	ResponseModeQueryJWT,
+	ResponseModeFragmentJWT,
+	ResponseModeFormPostJWT,
+}
+
+func TestResponseMode(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.AllowedAuthRequestTypes = []string{"code", "token"}
+	server.Config.AllowedResponseModes = testResponseModes
+
+	var tests = []struct {
+		rt       string
+		mode     string
+		expected string
+		err      string
+	}{
+		{"code", "", ResponseModeQuery, ""},
+		{"code", "fragment", ResponseModeFragment, ""},
+		{"code", "form_post", ResponseModeFormPost, ""},
+		{"token", "", ResponseModeFragment, ""},
+		{"token", "form_post", ResponseModeFormPost, ""},
+		{"token", "query", ResponseModeFragment, ErrInvalidRequest.Type},
+		{"token", "query.jwt", ResponseModeFragment, ErrInvalidRequest.Type},
+		{"code", "unknown", ResponseModeQuery, ErrInvalidRequest.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{
+			"response_type": {tt.rt},
+			"response_mode": {tt.mode},
+			"client_id":     {"1234"},
+			"state":         {"a"},
+		}
+		if ar := server.HandleAuthRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			server.FinishAuthRequest(resp, req, ar)
+		}
+
+		if tt.err == "" && resp.IsError {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+			continue
+		}
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+		if resp.ResponseType != REDIRECT || resp.ResponseMode != tt.expected {
+			t.Errorf("Expected redirect with response mode %s (%d), got: %s", tt.expected, i, resp.ResponseMode)
+		}
+		if resp.RedirectInFragment != (tt.expected == ResponseModeFragment) {
+			t.Errorf("Unexpected redirect in fragment (%d)", i)
+		}
+	}
+
+	// not allowed by default
+	server.Config.AllowedResponseModes = NewConfig().AllowedResponseModes
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"response_mode": {ResponseModeQueryJWT},
+		"client_id":     {"1234"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Authorization request should have failed")
+	}
+	if resp.ErrorType != ErrInvalidRequest.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrInvalidRequest.Type, resp.ErrorType)
+	}
+}
+
+func TestResponseModeFormPost(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.AllowedResponseModes = testResponseModes
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"response_mode": {ResponseModeFormPost},
+		"client_id":     {"1234"},
+		"state":         {"a"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	w := httptest.NewRecorder()
+	if err = WriteJSON(w, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status: %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("Unexpected content type: %s", ct)
+	}
+	if l := w.Header().Get("Location"); l != "" {
+		t.Fatalf("Should not redirect: %s", l)
+	}
+
+	body := w.Body.String()
+	for _, s := range []string{
+		`action="http://localhost:14000/appauth"`,
+		`name="code" value="1"`,
+		`name="state" value="a"`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("Form should contain %s: %s", s, body)
+		}
+	}
+}
+
+func TestResponseModeJWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t)
+	server.Config.AllowedAuthRequestTypes = []string{"code", "token"}
+	server.Config.AllowedResponseModes = testResponseModes
+	server.SigningKey = &JSONWebKey{Key: key, KeyID: "k1"}
+
+	var tests = []struct {
+		rt       string
+		mode     string
+		expected string
+		param    string
+	}{
+		{"code", ResponseModeJWT, ResponseModeQueryJWT, "code"},
+		{"code", ResponseModeFormPostJWT, ResponseModeFormPostJWT, "code"},
+		{"token", ResponseModeJWT, ResponseModeFragmentJWT, "access_token"},
+		{"code", ResponseModeQueryJWT, ResponseModeQueryJWT, "code"},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{
+			"response_type": {tt.rt},
+			"response_mode": {tt.mode},
+			"client_id":     {"1234"},
+			"state":         {"a"},
+		}
+		if ar := server.HandleAuthRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			server.FinishAuthRequest(resp, req, ar)
+		}
+
+		if resp.IsError {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+			continue
+		}
+		if resp.ResponseMode != tt.expected {
+			t.Errorf("Expected response mode %s (%d), got: %s", tt.expected, i, resp.ResponseMode)
+		}
+		if len(resp.Output) != 1 {
+			t.Errorf("Output should only have the response parameter (%d): %v", i, resp.Output)
+			continue
+		}
+
+		jt, err := parseJWT(resp.Output["response"].(string))
+		if err != nil {
+			t.Errorf("Could not parse response (%d): %v", i, err)
+			continue
+		}
+		if err = jt.verify(&key.PublicKey); err != nil {
+			t.Errorf("Could not verify response (%d): %v", i, err)
+			continue
+		}
+		c := jt.Claims
+		if c.String("iss") != "http://localhost:14000" || c.String("aud") != "1234" || c.String("state") != "a" || c.String(tt.param) == "" {
+			t.Errorf("Unexpected claims (%d): %v", i, c)
+		}
+		if _, ok := c["exp"]; !ok {
+			t.Errorf("Response should expire (%d)", i)
+		}
+	}
+
+	// denied requests are signed as well
+	resp := server.NewResponse()
+	req, err := http.NewRequest("GET", "http://localhost:14000/appauth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{
+		"response_type": {"code"},
+		"response_mode": {ResponseModeJWT},
+		"client_id":     {"1234"},
+		"state":         {"a"},
+	}
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		server.FinishAuthRequest(resp, req, ar)
+	}
+	jt, err := parseJWT(resp.Output["response"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := jt.Claims; c.String("error") != ErrAccessDenied.Type || c.String("state") != "a" {
+		t.Fatalf("Unexpected claims: %v", c)
+	}
+
+	// request errors are signed as well
+	resp = server.NewResponse()
+	req.Form.Set("code_challenge_method", PKCEMethodS256)
+	if ar := server.HandleAuthRequest(resp, req); ar != nil {
+		t.Fatalf("Request should have failed")
+	}
+	if jt, err = parseJWT(resp.Output["response"].(string)); err != nil {
+		t.Fatal(err)
+	}
+	if c := jt.Claims; c.String("error") != ErrInvalidRequest.Type {
+		t.Fatalf("Unexpected claims: %v", c)
+	}
+}
@@ -0,0 +1,135 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Token type hints, see:
+// http://tools.ietf.org/html/rfc7009#section-2.1
+const (
+	// AccessTokenHint is the access_token token type hint.
+	AccessTokenHint = "access_token"
+
+	// RefreshTokenHint is the refresh_token token type hint.
+	RefreshTokenHint = "refresh_token"
+)
+
+// RevocationRequest is a request to revoke a token, normally sent to
+// "/revoke" on the server.
+//
+// See http://tools.ietf.org/html/rfc7009
+type RevocationRequest struct {
+	// Token is the token to revoke.
+	Token string
+
+	// TokenTypeHint is the passed token type hint in the request.
+	TokenTypeHint string
+
+	// Client is the authenticated client information.
+	Client Client
+
+	// AccessGrant is the AccessGrant associated with Token. Nil if the token
+	// was not found.
+	AccessGrant *AccessGrant
+}
+
+// loadGrantByHint loads the AccessGrant for the access or refresh token,
+// trying the type given by hint first.
+func loadGrantByHint(storage Storage, token, hint string) *AccessGrant {
+	loaders := []func(string) (*AccessGrant, error){
+		storage.LoadAccessGrant,
+		storage.LoadRefreshGrant,
+	}
+	if hint == RefreshTokenHint {
+		loaders[0], loaders[1] = loaders[1], loaders[0]
+	}
+
+	for _, load := range loaders {
+		if ag, err := load(token); err == nil && ag != nil {
+			return ag
+		}
+	}
+
+	return nil
+}
+
+// HandleRevocationRequest is the http.HandlerFunc for handling token
+// revocation requests.
+func (s *Server) HandleRevocationRequest(w *Response, r *http.Request) *RevocationRequest {
+	if r.Method != "POST" {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("request must be POST")
+		return nil
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	ret := &RevocationRequest{
+		Token:         r.Form.Get("token"),
+		TokenTypeHint: r.Form.Get("token_type_hint"),
+	}
+
+	// "token" is required
+	if ret.Token == "" {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// invalid tokens do not cause an error response, see:
+	// http://tools.ietf.org/html/rfc7009#section-2.2
+	ret.AccessGrant = loadGrantByHint(w.Storage, ret.Token, ret.TokenTypeHint)
+	if ret.AccessGrant == nil {
+		return ret
+	}
+
+	// token must be from the client
+	if ret.AccessGrant.Client == nil || ret.AccessGrant.Client.GetID() != ret.Client.GetID() {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("token was issued to another client")
+		return nil
+	}
+
+	return ret
+}
+
+// FinishRevocationRequest finalizes the request handled by
+// HandleRevocationRequest, revoking both the access and refresh tokens of the
+// AccessGrant.
+func (s *Server) FinishRevocationRequest(w *Response, r *http.Request, rr *RevocationRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	// nothing to revoke
+	if rr.AccessGrant == nil {
+		return
+	}
+
+	// remove refresh token
+	if rr.AccessGrant.RefreshToken != "" {
+		if err := w.Storage.RemoveRefreshGrant(rr.AccessGrant.RefreshToken); err != nil {
+			w.SetError(ErrServerError)
+			w.InternalError = err
+			return
+		}
+	}
+
+	// remove access token
+	if err := w.Storage.RemoveAccessGrant(rr.AccessGrant.AccessToken); err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+}
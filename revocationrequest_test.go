@@ -0,0 +1,107 @@
+package oauthlib
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRevokeRefreshToken(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	server := NewServer(sconfig, storage)
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/revoke", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("token", "r9999")
+	req.Form.Set("token_type_hint", RefreshTokenHint)
+
+	if rr := server.HandleRevocationRequest(resp, req); rr != nil {
+		server.FinishRevocationRequest(resp, req, rr)
+	}
+
+	if resp.IsError && resp.InternalError != nil {
+		t.Fatalf("Error in response: %s", resp.InternalError)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error")
+	}
+
+	// both the refresh and the paired access token must be gone
+	if _, err := storage.LoadRefreshGrant("r9999"); err == nil {
+		t.Fatalf("Refresh token should have been revoked")
+	}
+
+	if _, err := storage.LoadAccessGrant("9999"); err == nil {
+		t.Fatalf("Access token should have been revoked")
+	}
+}
+
+func TestRevokeUnknownToken(t *testing.T) {
+	sconfig := NewConfig()
+	server := NewServer(sconfig, NewTestStorage(t))
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/revoke", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("token", "unknown")
+
+	if rr := server.HandleRevocationRequest(resp, req); rr != nil {
+		server.FinishRevocationRequest(resp, req, rr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Revoking an unknown token should not be an error")
+	}
+}
+
+func TestRevokeOtherClientToken(t *testing.T) {
+	sconfig := NewConfig()
+	storage := NewTestStorage(t)
+	err := storage.SetClient("5678", &DefaultClient{
+		ID:          "5678",
+		Secret:      "eeffgghh",
+		RedirectURI: "http://localhost:14000/otherauth",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/revoke", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("5678", "eeffgghh")
+
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("token", "9999")
+
+	if rr := server.HandleRevocationRequest(resp, req); rr != nil {
+		server.FinishRevocationRequest(resp, req, rr)
+	}
+
+	if !resp.IsError {
+		t.Fatalf("Revoking another client's token should be an error")
+	}
+
+	if _, err := storage.LoadAccessGrant("9999"); err != nil {
+		t.Fatalf("Access token should not have been revoked")
+	}
+}
@@ -26,6 +26,52 @@ type Server struct {
 	AuthorizeTokenGen AuthorizeTokenGen
 	AccessTokenGen    AccessTokenGen
 	Now               func() time.Time
+
+	ClientCredentialsGen ClientCredentialsGen
+	DeviceCodeGen        DeviceCodeGen
+	AuthReqIDGen         AuthReqIDGen
+
+	// AuthenticationDevice notifies end-users of backchannel authentication
+	// requests. If nil, backchannel authentication requests fail.
+	AuthenticationDevice AuthenticationDevice
+
+	// BackchannelNotifier sends the notifications of backchannel
+	// authentication clients in the ping and push modes.
+	BackchannelNotifier BackchannelNotifier
+
+	// LogoutNotifier sends logout tokens to the back-channel logout URIs of
+	// the clients of ended sessions.
+	LogoutNotifier LogoutNotifier
+
+	// DPoPNonceGen provides the nonces clients must include in DPoP proofs.
+	// If nil, nonces are not required.
+	DPoPNonceGen DPoPNonceGen
+
+	// RequestObjectLoader loads request objects passed by reference. If nil,
+	// request objects can only be passed by value.
+	RequestObjectLoader RequestObjectLoader
+
+	// TokenValidators validate the subject and actor tokens of token
+	// exchange requests not issued by this server, by token type.
+	TokenValidators map[string]TokenValidator
+
+	// AuthorizationDetailTypes are the supported authorization details
+	// types of rich authorization requests and their validators, by type.
+	// If nil, authorization details are rejected.
+	AuthorizationDetailTypes map[string]AuthorizationDetailValidator
+
+	// SigningKey is the private key used to sign ID tokens and UserInfo
+	// responses. If nil, ID tokens cannot be issued. Ignored if KeySet is
+	// set.
+	SigningKey *JSONWebKey
+
+	// KeySet manages the rotated signing keys of the server. If set, tokens
+	// are signed with the active key of the KeySet.
+	KeySet *KeySet
+
+	// ClaimsProvider provides the claims about end-users for UserInfo
+	// requests and ID tokens. If nil, only the "sub" claim is returned.
+	ClaimsProvider ClaimsProvider
 }
 
 // NewServer creates a new server instance
@@ -36,6 +82,12 @@ func NewServer(config *Config, storage Storage) *Server {
 		AuthorizeTokenGen: &AuthorizeTokenGenDefault{},
 		AccessTokenGen:    &AccessTokenGenDefault{},
 		Now:               time.Now,
+
+		ClientCredentialsGen: &ClientCredentialsGenDefault{},
+		DeviceCodeGen:        &DeviceCodeGenDefault{},
+		AuthReqIDGen:         &AuthReqIDGenDefault{},
+		BackchannelNotifier:  &BackchannelNotifierDefault{},
+		LogoutNotifier:       &LogoutNotifierDefault{},
 	}
 }
 
@@ -12,6 +12,39 @@ const (
 	goodBearerAuthValue = "Bearer BGFVTDUJDp0ZXN0"
 )
 
+// newTestServer creates a server using the test storage and predictable
+// token generation, allowing the grant types.
+func newTestServer(t *testing.T, grantTypes ...GrantType) *Server {
+	sconfig := NewConfig()
+	sconfig.Issuer = "http://localhost:14000"
+	if len(grantTypes) != 0 {
+		sconfig.AllowedGrantTypes = grantTypes
+	}
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.AuthorizeTokenGen = &TestingAuthorizeTokenGen{}
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+	return server
+}
+
+// doTestTokenRequest does a token request authenticating the client with the
+// test secret, authorizing the request.
+func doTestTokenRequest(t *testing.T, server *Server, clientID string, form url.Values) *Response {
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(clientID, "aabbccdd")
+	req.Form = form
+	req.PostForm = url.Values{}
+
+	if ar := server.HandleTokenRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishTokenRequest(resp, req, ar)
+	}
+	return resp
+}
+
 func TestGetClientAuth(t *testing.T) {
 	urlWithSecret, _ := url.Parse("http://host.tld/path?client_id=xxx&client_secret=yyy")
 	urlWithEmptySecret, _ := url.Parse("http://host.tld/path?client_id=xxx&client_secret=")
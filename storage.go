@@ -1,5 +1,7 @@
 package oauthlib
 
+import "time"
+
 // Storage interface
 type Storage interface {
 	// GetClient loads the client by id.
@@ -8,6 +10,9 @@ type Storage interface {
 	// SetClient saves Client with id to storage.
 	SetClient(id string, client Client) error
 
+	// RemoveClient deletes the Client with id from storage.
+	RemoveClient(id string) error
+
 	// SaveAuthorizeData saves the AuthorizeData to storage.
 	SaveAuthorizeData(*AuthorizeData) error
 
@@ -46,3 +51,96 @@ type Storage interface {
 	// RemoveRefreshGrant revokes or deletes refresh AccessGrant.
 	RemoveRefreshGrant(token string) error
 }
+
+// ReplayStorage is an optional interface storage can implement to detect
+// replayed JWTs.
+type ReplayStorage interface {
+	// SaveJTI saves the JWT id until expiration. Returns false if the JWT id
+	// was previously saved and has not expired.
+	SaveJTI(id string, expiration time.Time) (bool, error)
+}
+
+// DeviceStorage is an optional interface storage can implement to support the
+// device authorization grant.
+type DeviceStorage interface {
+	// SaveDeviceAuthorization saves the DeviceAuthorization to storage,
+	// replacing any previously saved DeviceAuthorization with the same device
+	// code.
+	SaveDeviceAuthorization(*DeviceAuthorization) error
+
+	// LoadDeviceAuthorization retrieves a DeviceAuthorization by device code.
+	//
+	// Client information MUST be loaded together.
+	LoadDeviceAuthorization(deviceCode string) (*DeviceAuthorization, error)
+
+	// LoadDeviceAuthorizationByUserCode retrieves a DeviceAuthorization by
+	// user code.
+	//
+	// Client information MUST be loaded together.
+	LoadDeviceAuthorizationByUserCode(userCode string) (*DeviceAuthorization, error)
+
+	// RemoveDeviceAuthorization deletes a DeviceAuthorization.
+	RemoveDeviceAuthorization(deviceCode string) error
+}
+
+// BackchannelStorage is an optional interface storage can implement to
+// support client initiated backchannel authentication.
+type BackchannelStorage interface {
+	// SaveBackchannelAuthentication saves the BackchannelAuthentication to
+	// storage, replacing any previously saved BackchannelAuthentication with
+	// the same authentication request id.
+	SaveBackchannelAuthentication(*BackchannelAuthentication) error
+
+	// LoadBackchannelAuthentication retrieves a BackchannelAuthentication by
+	// authentication request id.
+	//
+	// Client information MUST be loaded together.
+	LoadBackchannelAuthentication(authReqID string) (*BackchannelAuthentication, error)
+
+	// RemoveBackchannelAuthentication deletes a BackchannelAuthentication.
+	RemoveBackchannelAuthentication(authReqID string) error
+}
+
+// SessionStorage is an optional interface storage can implement to track
+// the login sessions of end-users, for logout.
+type SessionStorage interface {
+	// SaveSession saves the Session to storage, replacing any previously
+	// saved Session with the same id.
+	SaveSession(*Session) error
+
+	// LoadSession retrieves a Session by id. Returns ErrSessionNotFound if
+	// the Session does not exist.
+	LoadSession(id string) (*Session, error)
+
+	// RemoveSession deletes a Session.
+	RemoveSession(id string) error
+}
+
+// KeyStorage is an optional interface storage can implement to persist the
+// signing keys of a KeySet.
+type KeyStorage interface {
+	// SaveKey saves the Key to storage, replacing any previously saved Key
+	// with the same key id.
+	SaveKey(*Key) error
+
+	// LoadKeys retrieves all saved keys.
+	LoadKeys() ([]*Key, error)
+
+	// RemoveKey deletes a Key.
+	RemoveKey(kid string) error
+}
+
+// PushedAuthorizationStorage is an optional interface storage can implement to
+// support pushed authorization requests.
+type PushedAuthorizationStorage interface {
+	// SavePushedAuthorization saves the PushedAuthorization to storage.
+	SavePushedAuthorization(*PushedAuthorization) error
+
+	// LoadPushedAuthorization retrieves a PushedAuthorization by request uri.
+	//
+	// Client information MUST be loaded together.
+	LoadPushedAuthorization(requestURI string) (*PushedAuthorization, error)
+
+	// RemovePushedAuthorization deletes a PushedAuthorization.
+	RemovePushedAuthorization(requestURI string) error
+}
@@ -0,0 +1,241 @@
+package oauthlib
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Token type identifiers, see:
+// http://tools.ietf.org/html/rfc8693#section-3
+const (
+	// TokenTypeAccessToken is the token type of OAuth 2.0 access tokens.
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	// TokenTypeRefreshToken is the token type of OAuth 2.0 refresh tokens.
+	TokenTypeRefreshToken = "urn:ietf:params:oauth:token-type:refresh_token"
+
+	// TokenTypeIDToken is the token type of OpenID Connect ID tokens.
+	TokenTypeIDToken = "urn:ietf:params:oauth:token-type:id_token"
+
+	// TokenTypeJWT is the token type of JWTs.
+	TokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+
+	// TokenTypeSAML2 is the token type of SAML 2.0 assertions.
+	TokenTypeSAML2 = "urn:ietf:params:oauth:token-type:saml2"
+)
+
+// Actor is a party acting on behalf of the subject of a token. Nested actors
+// form the delegation chain, the most recent actor being outermost, see:
+// http://tools.ietf.org/html/rfc8693#section-4.1
+type Actor struct {
+	// Subject is the actor subject.
+	Subject string `json:"sub,omitempty"`
+
+	// ClientID is the client id of the actor, if the actor is a client.
+	ClientID string `json:"client_id,omitempty"`
+
+	// Actor is the prior actor in the delegation chain. Can be nil
+	Actor *Actor `json:"act,omitempty"`
+}
+
+// ExchangeToken is a validated subject or actor token of a token exchange
+// request.
+type ExchangeToken struct {
+	// Token is the token.
+	Token string
+
+	// TokenType is the token type identifier.
+	TokenType string
+
+	// Subject is the party the token represents.
+	Subject string
+
+	// ClientID is the client the token was issued to. Can be blank
+	ClientID string
+
+	// Scope is the scope of the token. Requested scopes must be within the
+	// scope of the subject token.
+	Scope string
+
+	// Audience is the audience the token is restricted to. Requested
+	// resources and audiences must be within the audience of the subject
+	// token. Blank if the token is not restricted.
+	Audience []string
+
+	// Actor is the delegation chain of the token. Can be nil
+	Actor *Actor
+
+	// AccessGrant is the AccessGrant of tokens issued by this server. Nil for
+	// external tokens.
+	AccessGrant *AccessGrant
+}
+
+// TokenValidator validates tokens of a token type not issued by this server,
+// for use in token exchange requests.
+type TokenValidator interface {
+	// ValidateToken validates the token, returning an error if the token is
+	// invalid.
+	ValidateToken(token, tokenType string) (*ExchangeToken, error)
+}
+
+// validateExchangeToken validates a subject or actor token. Access and refresh
+// tokens issued by this server are loaded from storage, other tokens are
+// validated by the Server.TokenValidators for the token type.
+func (s *Server) validateExchangeToken(storage Storage, token, tokenType string) (*ExchangeToken, error) {
+	var load func(string) (*AccessGrant, error)
+	switch tokenType {
+	case TokenTypeAccessToken:
+		load = storage.LoadAccessGrant
+	case TokenTypeRefreshToken:
+		load = storage.LoadRefreshGrant
+	}
+
+	if load != nil {
+		if ag, err := load(token); err == nil && ag != nil && ag.Client != nil {
+			if tokenType == TokenTypeAccessToken && ag.IsExpiredAt(s.Now()) {
+				return nil, errors.New("token expired")
+			}
+			if tokenType == TokenTypeRefreshToken && s.isRefreshExpiredAt(ag, s.Now()) {
+				return nil, errors.New("refresh token expired")
+			}
+			return &ExchangeToken{
+				Token:       token,
+				TokenType:   tokenType,
+				Subject:     ag.Subject,
+				ClientID:    ag.Client.GetID(),
+				Scope:       ag.Scope,
+				Audience:    ag.Audience,
+				Actor:       ag.Actor,
+				AccessGrant: ag,
+			}, nil
+		}
+	}
+
+	v, ok := s.TokenValidators[tokenType]
+	if !ok {
+		return nil, errors.New("unsupported token type: " + tokenType)
+	}
+	et, err := v.ValidateToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	if et == nil {
+		return nil, errors.New("invalid token")
+	}
+	return et, nil
+}
+
+func (s *Server) handleTokenExchangeRequest(w *Response, r *http.Request) *TokenRequest {
+	// generate access token
+	ret := &TokenRequest{
+		GrantType:          TokenExchangeGrant,
+		Scope:              r.Form.Get("scope"),
+		Resource:           r.Form["resource"],
+		RequestedTokenType: r.Form.Get("requested_token_type"),
+		GenerateRefresh:    false,
+		Expiration:         s.Config.AccessExpiration,
+	}
+
+	// "subject_token" and "subject_token_type" are required, "actor_token_type"
+	// is required with "actor_token", see:
+	// http://tools.ietf.org/html/rfc8693#section-2.1
+	subjectToken, subjectTokenType := r.Form.Get("subject_token"), r.Form.Get("subject_token_type")
+	actorToken, actorTokenType := r.Form.Get("actor_token"), r.Form.Get("actor_token_type")
+	if subjectToken == "" || subjectTokenType == "" || (actorToken == "") != (actorTokenType == "") {
+		w.SetError(ErrInvalidRequest)
+		return nil
+	}
+
+	// only access tokens can be issued
+	if ret.RequestedTokenType != "" && ret.RequestedTokenType != TokenTypeAccessToken {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = errors.New("unsupported requested token type: " + ret.RequestedTokenType)
+		return nil
+	}
+
+	// must have a valid client
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients cannot use the token exchange grant
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use the token exchange grant")
+		return nil
+	}
+
+	// must have valid tokens
+	var err error
+	if ret.SubjectToken, err = s.validateExchangeToken(w.Storage, subjectToken, subjectTokenType); err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+	if actorToken != "" {
+		if ret.ActorToken, err = s.validateExchangeToken(w.Storage, actorToken, actorTokenType); err != nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+	}
+
+	// tokens bound to a client certificate must be exchanged over that
+	// certificate; tokens bound to a dpop key are checked with the dpop proof
+	// of the request
+	for _, et := range []*ExchangeToken{ret.SubjectToken, ret.ActorToken} {
+		if et == nil || et.AccessGrant == nil {
+			continue
+		}
+		if err = checkCertificateBinding(et.AccessGrant, r); err != nil {
+			w.SetError(ErrInvalidRequest)
+			w.InternalError = err
+			return nil
+		}
+	}
+
+	// requested scope must be within the subject token scope
+	if ret.Scope == "" {
+		ret.Scope = ret.SubjectToken.Scope
+	}
+	if !containsAll(splitScope(ret.SubjectToken.Scope), splitScope(ret.Scope)) {
+		w.SetError(ErrInvalidScope)
+		w.InternalError = errors.New("the requested scope must not include any scope not granted to the subject token")
+		return nil
+	}
+
+	// delegation adds the actor to the chain, impersonation keeps the
+	// subject token chain
+	ret.Subject = ret.SubjectToken.Subject
+	ret.Actor = ret.SubjectToken.Actor
+	if ret.ActorToken != nil {
+		ret.Actor = &Actor{
+			Subject:  ret.ActorToken.Subject,
+			ClientID: ret.ActorToken.ClientID,
+			Actor:    ret.SubjectToken.Actor,
+		}
+	}
+
+	// requested resources and audiences must be within the subject token
+	// audience, the token is restricted to the subject token audience if none
+	// is requested
+	ret.Audience = append(append([]string(nil), ret.Resource...), r.Form["audience"]...)
+	if len(ret.SubjectToken.Audience) != 0 {
+		if !containsAll(ret.SubjectToken.Audience, ret.Audience) {
+			w.SetError(ErrInvalidTarget)
+			w.InternalError = errors.New("the requested audience must not include any audience not granted to the subject token")
+			return nil
+		}
+		if len(ret.Audience) == 0 {
+			ret.Audience = ret.SubjectToken.Audience
+		}
+	}
+
+	// set rest of data
+	ret.RedirectURI = firstURI(ret.Client.GetRedirectURI(), s.Config.RedirectURISeparator)
+	if ret.SubjectToken.AccessGrant != nil {
+		ret.UserData = ret.SubjectToken.AccessGrant.UserData
+	}
+
+	return ret
+}
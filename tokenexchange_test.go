@@ -0,0 +1,281 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testTokenValidator struct{}
+
+func (v *testTokenValidator) ValidateToken(token, tokenType string) (*ExchangeToken, error) {
+	if token != "external" {
+		return nil, errors.New("invalid token")
+	}
+	return &ExchangeToken{
+		Token:     token,
+		TokenType: tokenType,
+		Subject:   "service",
+		Scope:     "read",
+		Actor:     &Actor{Subject: "gateway"},
+	}, nil
+}
+
+func TestTokenExchange(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{TokenExchangeGrant}
+	storage := NewTestStorage(t)
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      storage.Clients["1234"],
+		AccessToken: "subject",
+		ExpiresIn:   3600,
+		Scope:       "read,write",
+		Subject:     "user",
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+	server.TokenValidators = map[string]TokenValidator{TokenTypeJWT: &testTokenValidator{}}
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("1234", "aabbccdd")
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(TokenExchangeGrant))
+	req.Form.Set("subject_token", "subject")
+	req.Form.Set("subject_token_type", TokenTypeAccessToken)
+	req.Form.Set("actor_token", "external")
+	req.Form.Set("actor_token_type", TokenTypeJWT)
+	req.Form.Set("scope", "read")
+	req.Form.Set("resource", "https://api.example.com")
+	req.Form.Set("audience", "backend")
+
+	if tr := server.HandleTokenRequest(resp, req); tr != nil {
+		tr.Authorized = true
+		server.FinishTokenRequest(resp, req, tr)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["issued_token_type"]; d != TokenTypeAccessToken {
+		t.Fatalf("Unexpected issued token type: %s", d)
+	}
+
+	if _, ok := resp.Output["refresh_token"]; ok {
+		t.Fatalf("Refresh token should not be issued")
+	}
+
+	ag, err := storage.LoadAccessGrant("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ag.Subject != "user" || ag.Scope != "read" {
+		t.Fatalf("Unexpected access grant: %+v", ag)
+	}
+
+	if len(ag.Audience) != 2 || ag.Audience[0] != "https://api.example.com" || ag.Audience[1] != "backend" {
+		t.Fatalf("Unexpected audience: %v", ag.Audience)
+	}
+
+	// actor is added to the actor token delegation chain of the subject
+	if ag.Actor == nil || ag.Actor.Subject != "service" || ag.Actor.Actor != nil {
+		t.Fatalf("Unexpected actor: %+v", ag.Actor)
+	}
+}
+
+func TestTokenExchangeInvalid(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{TokenExchangeGrant}
+	server := NewServer(sconfig, NewTestStorage(t))
+	server.TokenValidators = map[string]TokenValidator{TokenTypeJWT: &testTokenValidator{}}
+
+	var tests = []struct {
+		params url.Values
+		err    *ResponseError
+	}{
+		{url.Values{"subject_token": {"9999"}}, ErrInvalidRequest},
+		{url.Values{"subject_token": {"9999"}, "subject_token_type": {TokenTypeAccessToken}, "actor_token": {"external"}}, ErrInvalidRequest},
+		{url.Values{"subject_token": {"unknown"}, "subject_token_type": {TokenTypeAccessToken}}, ErrInvalidRequest},
+		{url.Values{"subject_token": {"9999"}, "subject_token_type": {TokenTypeSAML2}}, ErrInvalidRequest},
+		{url.Values{"subject_token": {"invalid"}, "subject_token_type": {TokenTypeJWT}}, ErrInvalidRequest},
+		{url.Values{"subject_token": {"external"}, "subject_token_type": {TokenTypeJWT}, "scope": {"write"}}, ErrInvalidScope},
+		{url.Values{"subject_token": {"external"}, "subject_token_type": {TokenTypeJWT}, "requested_token_type": {TokenTypeIDToken}}, ErrInvalidRequest},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = tt.params
+		req.Form.Set("grant_type", string(TokenExchangeGrant))
+		req.PostForm = url.Values{}
+
+		if tr := server.HandleTokenRequest(resp, req); tr != nil {
+			t.Errorf("Token exchange should have failed (%d)", i)
+			continue
+		}
+
+		if resp.ErrorType != tt.err.Type {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err.Type, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestTokenExchangeBoundTokens(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jkt, err := JSONWebKey{Key: &key.PublicKey}.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{TokenExchangeGrant}
+	storage := NewTestStorage(t)
+	public := &DefaultClient{ID: "5678", Type: ClientTypePublic, RedirectURI: "http://localhost:14000/otherauth"}
+	for _, ag := range []*AccessGrant{
+		{Client: storage.Clients["1234"], AccessToken: "dpop", DPoPThumbprint: jkt, CreatedAt: time.Now()},
+		{Client: storage.Clients["1234"], AccessToken: "mtls", CertificateThumbprint: "x5t", CreatedAt: time.Now()},
+		{Client: public, AccessToken: "public", RefreshToken: "rpublic", CreatedAt: time.Now().Add(-48 * time.Hour)},
+	} {
+		ag.ExpiresIn, ag.Scope, ag.Subject = 3600, "read", "user"
+		if err = storage.SaveAccessGrant(ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewServer(sconfig, storage)
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	var tests = []struct {
+		token     string
+		tokenType string
+		proofKey  *ecdsa.PrivateKey
+		err       string
+	}{
+		{"dpop", TokenTypeAccessToken, nil, ErrInvalidDPoPProof.Type},
+		{"dpop", TokenTypeAccessToken, otherKey, ErrInvalidGrant.Type},
+		{"dpop", TokenTypeAccessToken, key, ""},
+		{"mtls", TokenTypeAccessToken, nil, ErrInvalidRequest.Type},
+		{"rpublic", TokenTypeRefreshToken, nil, ErrInvalidRequest.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = url.Values{
+			"grant_type":         {string(TokenExchangeGrant)},
+			"subject_token":      {tt.token},
+			"subject_token_type": {tt.tokenType},
+		}
+		req.PostForm = url.Values{}
+		if tt.proofKey != nil {
+			req.Header.Set("DPoP", newTestDPoPProof(t, tt.proofKey, "POST", "http://localhost:14000/token", "", ""))
+		}
+
+		if tr := server.HandleTokenRequest(resp, req); tr != nil {
+			tr.Authorized = true
+			server.FinishTokenRequest(resp, req, tr)
+		}
+
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestTokenExchangeNarrowing(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{TokenExchangeGrant}
+	storage := NewTestStorage(t)
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      storage.Clients["1234"],
+		AccessToken: "subject",
+		ExpiresIn:   3600,
+		Scope:       "read write",
+		Subject:     "user",
+		Audience:    []string{"https://a.example.com", "backend"},
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	var tests = []struct {
+		params url.Values
+		scope  string
+		aud    []string
+		err    string
+	}{
+		{url.Values{"scope": {"read"}}, "read", []string{"https://a.example.com", "backend"}, ""},
+		{url.Values{"scope": {"write read"}}, "write read", []string{"https://a.example.com", "backend"}, ""},
+		{url.Values{"scope": {"read admin"}}, "", nil, ErrInvalidScope.Type},
+		{url.Values{"resource": {"https://a.example.com"}}, "read write", []string{"https://a.example.com"}, ""},
+		{url.Values{"audience": {"backend"}}, "read write", []string{"backend"}, ""},
+		{url.Values{"resource": {"https://b.example.com"}}, "", nil, ErrInvalidTarget.Type},
+		{url.Values{"audience": {"other"}}, "", nil, ErrInvalidTarget.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("1234", "aabbccdd")
+		req.Form = tt.params
+		req.Form.Set("grant_type", string(TokenExchangeGrant))
+		req.Form.Set("subject_token", "subject")
+		req.Form.Set("subject_token_type", TokenTypeAccessToken)
+		req.PostForm = url.Values{}
+
+		tr := server.HandleTokenRequest(resp, req)
+		if tt.err != "" {
+			if tr != nil || resp.ErrorType != tt.err {
+				t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+			}
+			continue
+		}
+
+		if tr == nil {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+			continue
+		}
+
+		if tr.Scope != tt.scope {
+			t.Errorf("Unexpected scope (%d): %q", i, tr.Scope)
+		}
+
+		if len(tr.Audience) != len(tt.aud) || !containsAll(tt.aud, tr.Audience) {
+			t.Errorf("Unexpected audience (%d): %v", i, tr.Audience)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package oauthlib
 
 import (
+	"crypto/rand"
 	"encoding/base64"
 	"strings"
 
@@ -36,3 +37,66 @@ func (a *AccessTokenGenDefault) GenerateAccessToken(data *AccessGrant, generater
 	}
 	return
 }
+
+// ClientCredentialsGenDefault is the default client credentials generator
+type ClientCredentialsGenDefault struct {
+}
+
+// GenerateClientCredentials generates base64-encoded UUID client id and secret
+func (a *ClientCredentialsGenDefault) GenerateClientCredentials(md *ClientMetadata) (id string, secret string, err error) {
+	token := uuid.NewRandom()
+	id = removePadding(base64.URLEncoding.EncodeToString([]byte(token)))
+
+	stoken := uuid.NewRandom()
+	secret = removePadding(base64.URLEncoding.EncodeToString([]byte(stoken)))
+	return
+}
+
+// GenerateRegistrationAccessToken generates a base64-encoded UUID registration
+// access token
+func (a *ClientCredentialsGenDefault) GenerateRegistrationAccessToken(client Client) (string, error) {
+	token := uuid.NewRandom()
+	return removePadding(base64.URLEncoding.EncodeToString([]byte(token))), nil
+}
+
+// userCodeChars are the characters used in user codes, see:
+// http://tools.ietf.org/html/rfc8628#section-6.1
+const userCodeChars = "BCDFGHJKLMNPQRSTVWXZ"
+
+// DeviceCodeGenDefault is the default device code generator
+type DeviceCodeGenDefault struct {
+}
+
+// GenerateDeviceCode generates a base64-encoded UUID device code and an 8
+// character user code
+func (a *DeviceCodeGenDefault) GenerateDeviceCode(data *DeviceAuthorization) (devicecode string, usercode string, err error) {
+	token := uuid.NewRandom()
+	devicecode = removePadding(base64.URLEncoding.EncodeToString([]byte(token)))
+
+	// discard bytes past the last full cycle of the characters, so each
+	// character is equally likely
+	limit := 256 - 256%len(userCodeChars)
+	code, buf := make([]byte, 0, 8), make([]byte, 8)
+	for len(code) < cap(code) {
+		if _, err = rand.Read(buf); err != nil {
+			return "", "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < cap(code) {
+				code = append(code, userCodeChars[int(b)%len(userCodeChars)])
+			}
+		}
+	}
+	usercode = string(code)
+	return
+}
+
+// AuthReqIDGenDefault is the default authentication request id generator
+type AuthReqIDGenDefault struct {
+}
+
+// GenerateAuthReqID generates a base64-encoded UUID authentication request id
+func (a *AuthReqIDGenDefault) GenerateAuthReqID(data *BackchannelAuthentication) (string, error) {
+	token := uuid.NewRandom()
+	return removePadding(base64.URLEncoding.EncodeToString([]byte(token))), nil
+}
@@ -28,8 +28,23 @@ const (
 	ClientCredentialsGrant GrantType = "client_credentials"
 
 	// AssertionGrant is the assertion grant type.
+	//
+	// Deprecated: use JWTBearerGrant instead.
 	AssertionGrant GrantType = "assertion"
 
+	// JWTBearerGrant is the JWT authorization grant type.
+	JWTBearerGrant GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// DeviceCodeGrant is the device authorization grant type.
+	DeviceCodeGrant GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// TokenExchangeGrant is the token exchange grant type.
+	TokenExchangeGrant GrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// CIBAGrant is the client initiated backchannel authentication grant
+	// type.
+	CIBAGrant GrantType = "urn:openid:params:grant-type:ciba"
+
 	// ImplicitGrant is the __implicit grant type.
 	ImplicitGrant GrantType = "__implicit"
 )
@@ -54,6 +69,13 @@ type TokenRequest struct {
 	// AccessGrant is the provided access grant.
 	AccessGrant *AccessGrant
 
+	// DeviceAuthorization is the device authorization, for device code.
+	DeviceAuthorization *DeviceAuthorization
+
+	// BackchannelAuthentication is the backchannel authentication, for
+	// CIBA.
+	BackchannelAuthentication *BackchannelAuthentication
+
 	// ForceAccessGrant if provided forces finish to use this access data, to
 	// allow access data reuse.
 	ForceAccessGrant *AccessGrant
@@ -76,6 +98,69 @@ type TokenRequest struct {
 	// Assertion is the provided assertion in the request.
 	Assertion string
 
+	// AssertionClaims are the validated claims of a JWT assertion.
+	AssertionClaims JWTClaims
+
+	// Subject is the resource owner the token is issued for.
+	Subject string
+
+	// CodeVerifier is the provided PKCE code verifier in the request.
+	CodeVerifier string
+
+	// CertificateThumbprint is the thumbprint of the mutual TLS client
+	// certificate the token will be bound to. Blank if the token is not
+	// bound.
+	CertificateThumbprint string
+
+	// DPoPThumbprint is the thumbprint of the DPoP proof key the token will
+	// be bound to. Blank if the token is not bound.
+	DPoPThumbprint string
+
+	// SubjectToken is the validated subject token, for token exchange.
+	SubjectToken *ExchangeToken
+
+	// ActorToken is the validated actor token, for token exchange. Can be
+	// nil
+	ActorToken *ExchangeToken
+
+	// Actor is the delegation chain of the token. Can be nil
+	Actor *Actor
+
+	// Resource are the requested resource URIs.
+	Resource []string
+
+	// Audience is the intended audience of the token, the requested resource
+	// URIs and logical audience names. Change if a different audience should
+	// be issued.
+	Audience []string
+
+	// AuthorizationDetails are the authorization details of the token. Can
+	// be nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// RequestedTokenType is the requested token type identifier, for token
+	// exchange.
+	RequestedTokenType string
+
+	// GenerateIDToken toggles if an OpenID Connect ID token should be
+	// generated, for requests with the openid scope to servers with a
+	// signing key.
+	GenerateIDToken bool
+
+	// Nonce is the OpenID Connect nonce of the authorization request.
+	Nonce string
+
+	// AuthTime is the time the resource owner authenticated.
+	AuthTime time.Time
+
+	// SessionID is the id of the end-user's login session the tokens are
+	// issued in. Can be blank
+	SessionID string
+
+	// Claims are the individual claims requested in the authorization
+	// request. Can be nil
+	Claims *ClaimsRequest
+
 	// Authorized toggles if request is authorized.
 	Authorized bool
 
@@ -112,6 +197,41 @@ type AccessGrant struct {
 	// Requested scope
 	Scope string
 
+	// Resource owner the token was issued for. Can be blank
+	Subject string
+
+	// Thumbprint of the mutual TLS client certificate the token is bound to
+	// ("x5t#S256" confirmation). Can be blank
+	CertificateThumbprint string
+
+	// Thumbprint of the DPoP proof key the token is bound to ("jkt"
+	// confirmation). Can be blank
+	DPoPThumbprint string
+
+	// Delegation chain of the token. Can be nil
+	Actor *Actor
+
+	// Intended audience of the token. Can be blank
+	Audience []string
+
+	// Resource URIs the grant is restricted to. Tokens of refresh requests
+	// can be restricted to a subset of these. Can be blank
+	Resource []string
+
+	// Authorization details of the token. Can be nil
+	AuthorizationDetails []AuthorizationDetail
+
+	// Authorization details the grant is restricted to. Tokens of refresh
+	// requests can be restricted to a subset of these. Can be nil
+	GrantedAuthorizationDetails []AuthorizationDetail
+
+	// Individual claims requested in the authorization request. Can be nil
+	Claims *ClaimsRequest
+
+	// Id of the end-user's login session the token was issued in. Can be
+	// blank
+	SessionID string
+
 	// Redirect URI from request
 	RedirectURI string
 
@@ -163,35 +283,56 @@ func (s *Server) HandleTokenRequest(w *Response, r *http.Request) *TokenRequest
 		return nil
 	}
 
+	var ret *TokenRequest
 	switch grantType {
 	case AuthorizationCodeGrant:
-		return s.handleAuthorizationCodeRequest(w, r)
+		ret = s.handleAuthorizationCodeRequest(w, r)
 	case RefreshTokenGrant:
-		return s.handleRefreshTokenRequest(w, r)
+		ret = s.handleRefreshTokenRequest(w, r)
 	case PasswordGrant:
-		return s.handlePasswordRequest(w, r)
+		ret = s.handlePasswordRequest(w, r)
 	case ClientCredentialsGrant:
-		return s.handleClientCredentialsRequest(w, r)
+		ret = s.handleClientCredentialsRequest(w, r)
 	case AssertionGrant:
-		return s.handleAssertionRequest(w, r)
+		ret = s.handleAssertionRequest(w, r)
+	case DeviceCodeGrant:
+		ret = s.handleDeviceCodeRequest(w, r)
+	case JWTBearerGrant:
+		ret = s.handleJWTBearerRequest(w, r)
+	case TokenExchangeGrant:
+		ret = s.handleTokenExchangeRequest(w, r)
+	case CIBAGrant:
+		ret = s.handleBackchannelRequest(w, r)
+	default:
+		w.SetError(ErrUnsupportedGrantType)
+		return nil
 	}
 
-	w.SetError(ErrUnsupportedGrantType)
-	return nil
-}
-
-func (s *Server) handleAuthorizationCodeRequest(w *Response, r *http.Request) *TokenRequest {
-	// get client authentication
-	auth := s.getClientAuth(w, r)
-	if auth == nil {
+	// restrict token to the requested resources and authorization details
+	if ret != nil && (!s.handleResources(w, r, ret) || !s.handleAuthorizationDetails(w, r, ret)) {
 		return nil
 	}
 
+	// bind token to the client certificate and dpop key
+	if ret != nil {
+		ret.CertificateThumbprint = s.certificateBinding(ret.Client, r)
+
+		var ok bool
+		if ret.DPoPThumbprint, ok = s.dpopBinding(w, r, ret); !ok {
+			return nil
+		}
+	}
+
+	return ret
+}
+
+func (s *Server) handleAuthorizationCodeRequest(w *Response, r *http.Request) *TokenRequest {
 	// generate access token
 	ret := &TokenRequest{
 		GrantType:       AuthorizationCodeGrant,
 		Code:            r.Form.Get("code"),
 		RedirectURI:     r.Form.Get("redirect_uri"),
+		CodeVerifier:    r.Form.Get("code_verifier"),
 		GenerateRefresh: true,
 		Expiration:      s.Config.AccessExpiration,
 		//HttpRequest:     r,
@@ -204,7 +345,7 @@ func (s *Server) handleAuthorizationCodeRequest(w *Response, r *http.Request) *T
 	}
 
 	// must have a valid client
-	if ret.Client = getClient(auth, w.Storage, w); ret.Client == nil {
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
 		return nil
 	}
 
@@ -254,13 +395,73 @@ func (s *Server) handleAuthorizationCodeRequest(w *Response, r *http.Request) *T
 		return nil
 	}
 
+	// client must use pkce when required
+	if ret.AuthorizeData.CodeChallenge == "" && s.Config.isPKCERequired(ret.Client) {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("code challenge required")
+		return nil
+	}
+
+	// verify pkce code verifier
+	if ret.AuthorizeData.CodeChallenge != "" {
+		if ret.CodeVerifier == "" {
+			w.SetError(ErrInvalidGrant)
+			w.InternalError = errors.New("code verifier required")
+			return nil
+		}
+		if err = verifyCodeChallenge(ret.AuthorizeData.CodeChallenge, ret.AuthorizeData.CodeChallengeMethod, ret.CodeVerifier); err != nil {
+			w.SetError(ErrInvalidGrant)
+			w.InternalError = err
+			return nil
+		}
+	} else if ret.CodeVerifier != "" {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("code verifier sent without code challenge")
+		return nil
+	}
+
+	// session of the code must not have ended
+	if err = checkSession(w.Storage, ret.AuthorizeData.SessionID); err == ErrSessionNotFound {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("session ended")
+		return nil
+	} else if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil
+	}
+
 	// set rest of data
 	ret.Scope = ret.AuthorizeData.Scope
+	ret.Subject = ret.AuthorizeData.Subject
 	ret.UserData = ret.AuthorizeData.UserData
+	ret.GenerateIDToken = hasScope(ret.Scope, ScopeOpenID) && s.hasSigningKey()
+	ret.Nonce = ret.AuthorizeData.Nonce
+	ret.AuthTime = ret.AuthorizeData.AuthTime
+	ret.SessionID = ret.AuthorizeData.SessionID
+	ret.Claims = ret.AuthorizeData.Claims
 
 	return ret
 }
 
+// refreshExpireAt returns the expiration date of the refresh token of the
+// access grant, and whether it expires. Only refresh tokens of public clients
+// expire, see:
+// http://tools.ietf.org/html/draft-ietf-oauth-security-topics#section-4.14
+func (s *Server) refreshExpireAt(ag *AccessGrant) (time.Time, bool) {
+	if !isPublicClient(ag.Client) || s.Config.PublicRefreshExpiration <= 0 {
+		return time.Time{}, false
+	}
+	return ag.CreatedAt.Add(time.Duration(s.Config.PublicRefreshExpiration) * time.Second), true
+}
+
+// isRefreshExpiredAt determines if the refresh token of the access grant
+// expires at time t.
+func (s *Server) isRefreshExpiredAt(ag *AccessGrant, t time.Time) bool {
+	exp, ok := s.refreshExpireAt(ag)
+	return ok && t.After(exp)
+}
+
 func extraScopes(accessScopes, refreshScopes string) bool {
 	accessScopesList := strings.Split(accessScopes, ",")
 	refreshScopesList := strings.Split(refreshScopes, ",")
@@ -286,12 +487,6 @@ func extraScopes(accessScopes, refreshScopes string) bool {
 }
 
 func (s *Server) handleRefreshTokenRequest(w *Response, r *http.Request) *TokenRequest {
-	// get client authentication
-	auth := s.getClientAuth(w, r)
-	if auth == nil {
-		return nil
-	}
-
 	// generate access token
 	ret := &TokenRequest{
 		GrantType:       RefreshTokenGrant,
@@ -308,7 +503,7 @@ func (s *Server) handleRefreshTokenRequest(w *Response, r *http.Request) *TokenR
 	}
 
 	// must have a valid client
-	if ret.Client = getClient(auth, w.Storage, w); ret.Client == nil {
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
 		return nil
 	}
 
@@ -341,8 +536,26 @@ func (s *Server) handleRefreshTokenRequest(w *Response, r *http.Request) *TokenR
 
 	}
 
+	// refresh token must be sent over the same client certificate, see:
+	// http://tools.ietf.org/html/rfc8705#section-4
+	if err = checkCertificateBinding(ret.AccessGrant, r); err != nil {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = err
+		return nil
+	}
+
+	// refresh tokens of public clients expire
+	if s.isRefreshExpiredAt(ret.AccessGrant, s.Now()) {
+		w.SetError(ErrInvalidGrant)
+		w.InternalError = errors.New("refresh token expired")
+		return nil
+	}
+
 	// set rest of data
 	ret.RedirectURI = ret.AccessGrant.RedirectURI
+	ret.Subject = ret.AccessGrant.Subject
+	ret.Claims = ret.AccessGrant.Claims
+	ret.SessionID = ret.AccessGrant.SessionID
 	ret.UserData = ret.AccessGrant.UserData
 	if ret.Scope == "" {
 		ret.Scope = ret.AccessGrant.Scope
@@ -376,12 +589,6 @@ func (s *Server) getClientAuth(w *Response, r *http.Request) *BasicAuth {
 }
 
 func (s *Server) handlePasswordRequest(w *Response, r *http.Request) *TokenRequest {
-	// get client auth
-	auth := s.getClientAuth(w, r)
-	if auth == nil {
-		return nil
-	}
-
 	// generate access token
 	ret := &TokenRequest{
 		GrantType:       PasswordGrant,
@@ -400,7 +607,7 @@ func (s *Server) handlePasswordRequest(w *Response, r *http.Request) *TokenReque
 	}
 
 	// must have a valid client
-	if ret.Client = getClient(auth, w.Storage, w); ret.Client == nil {
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
 		return nil
 	}
 
@@ -411,12 +618,6 @@ func (s *Server) handlePasswordRequest(w *Response, r *http.Request) *TokenReque
 }
 
 func (s *Server) handleClientCredentialsRequest(w *Response, r *http.Request) *TokenRequest {
-	// get client authentication
-	auth := s.getClientAuth(w, r)
-	if auth == nil {
-		return nil
-	}
-
 	// generate access token
 	ret := &TokenRequest{
 		GrantType:       ClientCredentialsGrant,
@@ -427,7 +628,15 @@ func (s *Server) handleClientCredentialsRequest(w *Response, r *http.Request) *T
 	}
 
 	// must have a valid client
-	if ret.Client = getClient(auth, w.Storage, w); ret.Client == nil {
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients cannot use the client credentials grant, see:
+	// http://tools.ietf.org/html/rfc6749#section-4.4
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use the client credentials grant")
 		return nil
 	}
 
@@ -438,12 +647,6 @@ func (s *Server) handleClientCredentialsRequest(w *Response, r *http.Request) *T
 }
 
 func (s *Server) handleAssertionRequest(w *Response, r *http.Request) *TokenRequest {
-	// get client authentication
-	auth := s.getClientAuth(w, r)
-	if auth == nil {
-		return nil
-	}
-
 	// generate access token
 	ret := &TokenRequest{
 		GrantType:       AssertionGrant,
@@ -462,7 +665,14 @@ func (s *Server) handleAssertionRequest(w *Response, r *http.Request) *TokenRequ
 	}
 
 	// must have a valid client
-	if ret.Client = getClient(auth, w.Storage, w); ret.Client == nil {
+	if ret.Client = s.authenticateClient(w, r); ret.Client == nil {
+		return nil
+	}
+
+	// public clients cannot use the assertion grant
+	if isPublicClient(ret.Client) {
+		w.SetError(ErrUnauthorizedClient)
+		w.InternalError = errors.New("public clients cannot use the assertion grant")
 		return nil
 	}
 
@@ -498,6 +708,26 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 				ExpiresIn:     ar.Expiration,
 				UserData:      ar.UserData,
 				Scope:         ar.Scope,
+				Subject:       ar.Subject,
+
+				CertificateThumbprint: ar.CertificateThumbprint,
+				DPoPThumbprint:        ar.DPoPThumbprint,
+				Actor:                 ar.Actor,
+				Audience:              ar.Audience,
+				Resource:              ar.Resource,
+				AuthorizationDetails:  ar.AuthorizationDetails,
+				Claims:                ar.Claims,
+				SessionID:             ar.SessionID,
+			}
+
+			// refreshed grants keep the resources and authorization details
+			// of the original grant
+			if granted := ar.grantedResources(); len(granted) != 0 || ar.AccessGrant != nil {
+				ret.Resource = granted
+			}
+			ret.GrantedAuthorizationDetails = ar.AuthorizationDetails
+			if granted := ar.grantedAuthorizationDetails(); len(granted) != 0 || ar.AccessGrant != nil {
+				ret.GrantedAuthorizationDetails = granted
 			}
 
 			// generate access token
@@ -511,6 +741,36 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 			ret = ar.ForceAccessGrant
 		}
 
+		// generate id token, see:
+		// http://openid.net/specs/openid-connect-core-1_0.html#TokenResponse
+		var idToken string
+		if ar.GenerateIDToken {
+			data := &IDToken{
+				Client:      ar.Client,
+				Subject:     ar.Subject,
+				Nonce:       ar.Nonce,
+				AuthTime:    ar.AuthTime,
+				SessionID:   ar.SessionID,
+				AccessToken: ret.AccessToken,
+				Scope:       ar.Scope,
+				Claims:      ar.Claims,
+				ExpiresIn:   s.Config.IDTokenExpiration,
+				CreatedAt:   s.Now(),
+			}
+			if ar.GrantType == ImplicitGrant {
+				data.Code = ar.Code
+			}
+			if ba := ar.BackchannelAuthentication; ba != nil && ba.DeliveryMode == BackchannelModePush {
+				data.AuthReqID = ba.AuthReqID
+				data.RefreshToken = ret.RefreshToken
+			}
+			if idToken, err = s.generateIDToken(data); err != nil {
+				w.SetError(ErrServerError)
+				w.InternalError = err
+				return
+			}
+		}
+
 		// save access token
 		if err = w.Storage.SaveAccessGrant(ret); err != nil {
 			w.SetError(ErrServerError)
@@ -518,6 +778,15 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 			return
 		}
 
+		// add token to the end-user's session
+		if ret.SessionID != "" {
+			if err = s.addToSession(w.Storage, ret.SessionID, ret.Subject, ret.Client, ret.AccessToken); err != nil {
+				w.SetError(ErrServerError)
+				w.InternalError = err
+				return
+			}
+		}
+
 		// remove authorization token
 		if ret.AuthorizeData != nil {
 			err := w.Storage.RemoveAuthorizeData(ret.AuthorizeData.Code)
@@ -527,6 +796,28 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 			}
 		}
 
+		// remove device authorization
+		if ar.DeviceAuthorization != nil {
+			if ds, ok := w.Storage.(DeviceStorage); ok {
+				err := ds.RemoveDeviceAuthorization(ar.DeviceAuthorization.DeviceCode)
+				if err != nil {
+					w.SetError(ErrServerError)
+					return
+				}
+			}
+		}
+
+		// remove backchannel authentication
+		if ar.BackchannelAuthentication != nil {
+			if bs, ok := w.Storage.(BackchannelStorage); ok {
+				err := bs.RemoveBackchannelAuthentication(ar.BackchannelAuthentication.AuthReqID)
+				if err != nil {
+					w.SetError(ErrServerError)
+					return
+				}
+			}
+		}
+
 		// remove previous access token
 		if ret.AccessGrant != nil {
 			if ret.AccessGrant.RefreshToken != "" {
@@ -545,7 +836,7 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 
 		// output data
 		w.Output["access_token"] = ret.AccessToken
-		w.Output["token_type"] = s.Config.TokenType
+		w.Output["token_type"] = s.tokenType(ret)
 		w.Output["expires_in"] = ret.ExpiresIn
 		if ret.RefreshToken != "" {
 			w.Output["refresh_token"] = ret.RefreshToken
@@ -553,6 +844,15 @@ func (s *Server) FinishTokenRequest(w *Response, r *http.Request, ar *TokenReque
 		if ar.Scope != "" {
 			w.Output["scope"] = ar.Scope
 		}
+		if len(ret.AuthorizationDetails) != 0 {
+			w.Output["authorization_details"] = ret.AuthorizationDetails
+		}
+		if ar.GrantType == TokenExchangeGrant {
+			w.Output["issued_token_type"] = TokenTypeAccessToken
+		}
+		if idToken != "" {
+			w.Output["id_token"] = idToken
+		}
 	} else {
 		w.SetError(ErrAccessDenied)
 	}
@@ -574,19 +874,9 @@ func getClient(auth *BasicAuth, storage Storage, w *Response) Client {
 		return nil
 	}
 
-	switch client := client.(type) {
-	case ClientSecretMatcher:
-		// Prefer the more secure method of giving the secret to the client for comparison
-		if !client.ClientSecretMatches(auth.Password) {
-			w.SetError(ErrUnauthorizedClient)
-			return nil
-		}
-	default:
-		// Fallback to the less secure method of extracting the plain text secret from the client for comparison
-		if client.GetSecret() != auth.Password {
-			w.SetError(ErrUnauthorizedClient)
-			return nil
-		}
+	if !clientSecretMatches(client, auth.Password) {
+		w.SetError(ErrUnauthorizedClient)
+		return nil
 	}
 
 	if client.GetRedirectURI() == "" {
@@ -595,3 +885,15 @@ func getClient(auth *BasicAuth, storage Storage, w *Response) Client {
 	}
 	return client
 }
+
+// clientSecretMatches determines if secret matches the client secret.
+func clientSecretMatches(client Client, secret string) bool {
+	switch client := client.(type) {
+	case ClientSecretMatcher:
+		// Prefer the more secure method of giving the secret to the client for comparison
+		return client.ClientSecretMatches(secret)
+	default:
+		// Fallback to the less secure method of extracting the plain text secret from the client for comparison
+		return client.GetSecret() == secret
+	}
+}
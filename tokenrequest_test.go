@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestAccessAuthorizationCode(t *testing.T) {
@@ -194,6 +195,110 @@ func TestAccessClientCredentials(t *testing.T) {
 	}
 }
 
+func TestAccessPublicClient(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{AuthorizationCodeGrant, RefreshTokenGrant, ClientCredentialsGrant, AssertionGrant, JWTBearerGrant, TokenExchangeGrant, CIBAGrant}
+	sconfig.RequirePKCE = PKCERequiredPublic
+	storage := NewTestStorage(t)
+	client := &DefaultClient{
+		ID:          "5678",
+		Type:        ClientTypePublic,
+		RedirectURI: "http://localhost:14000/otherauth",
+	}
+	if err := storage.SetClient("5678", client); err != nil {
+		t.Fatal(err)
+	}
+	for _, ad := range []*AuthorizeData{
+		{Code: "8888"},
+		{Code: "7777", CodeChallenge: testCodeChallenge, CodeChallengeMethod: PKCEMethodS256},
+	} {
+		ad.Client, ad.ExpiresIn, ad.CreatedAt, ad.RedirectURI = client, 3600, time.Now(), client.RedirectURI
+		if err := storage.SaveAuthorizeData(ad); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ag := range []*AccessGrant{
+		{AccessToken: "8888", RefreshToken: "r8888", CreatedAt: time.Now()},
+		{AccessToken: "7777", RefreshToken: "r7777", CreatedAt: time.Now().Add(-48 * time.Hour)},
+	} {
+		ag.Client, ag.ExpiresIn, ag.RedirectURI = client, 3600, client.RedirectURI
+		if err := storage.SaveAccessGrant(ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := NewServer(sconfig, storage)
+	server.AccessTokenGen = &TestingAccessTokenGen{}
+
+	var tests = []struct {
+		params map[string]string
+		err    string
+	}{
+		{map[string]string{"grant_type": "authorization_code", "code": "8888"}, ErrInvalidGrant.Type},
+		{map[string]string{"grant_type": "authorization_code", "code": "7777", "code_verifier": testCodeVerifier}, ""},
+		{map[string]string{"grant_type": "client_credentials"}, ErrUnauthorizedClient.Type},
+		{map[string]string{"grant_type": string(AssertionGrant), "assertion_type": "urn:test", "assertion": "x"}, ErrUnauthorizedClient.Type},
+		{map[string]string{"grant_type": string(JWTBearerGrant), "assertion": "x.y.z"}, ErrUnauthorizedClient.Type},
+		{map[string]string{"grant_type": string(TokenExchangeGrant), "subject_token": "8888", "subject_token_type": TokenTypeAccessToken}, ErrUnauthorizedClient.Type},
+		{map[string]string{"grant_type": string(CIBAGrant), "auth_req_id": "x"}, ErrUnauthorizedClient.Type},
+		{map[string]string{"grant_type": "refresh_token", "refresh_token": "r8888"}, ""},
+		{map[string]string{"grant_type": "refresh_token", "refresh_token": "r7777"}, ErrInvalidGrant.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		req.PostForm = url.Values{}
+		req.Form.Set("client_id", "5678")
+		for k, v := range tt.params {
+			req.Form.Set(k, v)
+		}
+
+		if ar := server.HandleTokenRequest(resp, req); ar != nil {
+			ar.Authorized = true
+			server.FinishTokenRequest(resp, req, ar)
+		}
+
+		if tt.err == "" && resp.IsError {
+			t.Errorf("Should not be an error (%d): %v", i, resp.InternalError)
+		} else if tt.err != "" && resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+	}
+}
+
+func TestAccessSecretlessClient(t *testing.T) {
+	sconfig := NewConfig()
+	sconfig.AllowedGrantTypes = []GrantType{ClientCredentialsGrant}
+	storage := NewTestStorage(t)
+	if err := storage.SetClient("5678", &DefaultClient{ID: "5678", RedirectURI: "http://localhost:14000/otherauth"}); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(sconfig, storage)
+
+	resp := server.NewResponse()
+	req, err := http.NewRequest("POST", "http://localhost:14000/token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth("5678", "")
+	req.Form = url.Values{}
+	req.PostForm = url.Values{}
+	req.Form.Set("grant_type", string(ClientCredentialsGrant))
+
+	if ar := server.HandleTokenRequest(resp, req); ar != nil {
+		ar.Authorized = true
+		server.FinishTokenRequest(resp, req, ar)
+	}
+
+	if resp.ErrorType != ErrUnauthorizedClient.Type {
+		t.Fatalf("Expected error %q, got: %q", ErrUnauthorizedClient.Type, resp.ErrorType)
+	}
+}
+
 func TestExtraScopes(t *testing.T) {
 	if extraScopes("", "") == true {
 		t.Fatalf("extraScopes returned true with empty scopes")
@@ -0,0 +1,250 @@
+package oauthlib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// scopeClaims are the claims requested by the OpenID Connect scopes, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#ScopeClaims
+var scopeClaims = map[string][]string{
+	"profile": {
+		"name", "family_name", "given_name", "middle_name", "nickname",
+		"preferred_username", "profile", "picture", "website", "gender",
+		"birthdate", "zoneinfo", "locale", "updated_at",
+	},
+	"email":   {"email", "email_verified"},
+	"address": {"address"},
+	"phone":   {"phone_number", "phone_number_verified"},
+}
+
+// ClaimRequest is the request for an individual claim, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#IndividualClaimsRequests
+type ClaimRequest struct {
+	// Essential toggles if the claim is essential.
+	Essential bool `json:"essential,omitempty"`
+
+	// Value is the requested value of the claim.
+	Value interface{} `json:"value,omitempty"`
+
+	// Values are the requested values of the claim, in order of preference.
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// ClaimsRequest is the "claims" authorization request parameter, requesting
+// individual claims to be returned from the UserInfo endpoint and in the ID
+// token, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#ClaimsParameter
+type ClaimsRequest struct {
+	// UserInfo are the claims requested from the UserInfo endpoint. Values
+	// are nil for claims requested in the default manner.
+	UserInfo map[string]*ClaimRequest `json:"userinfo,omitempty"`
+
+	// IDToken are the claims requested in the ID token. Values are nil for
+	// claims requested in the default manner.
+	IDToken map[string]*ClaimRequest `json:"id_token,omitempty"`
+}
+
+// parseClaimsRequest parses the "claims" request parameter. Returns nil if
+// the parameter is blank.
+func parseClaimsRequest(claims string) (*ClaimsRequest, error) {
+	if claims == "" {
+		return nil, nil
+	}
+	ret := new(ClaimsRequest)
+	if err := json.Unmarshal([]byte(claims), ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// claimNames returns the names of the claims requested by the scope and
+// individually, sorted.
+func claimNames(scope string, requested map[string]*ClaimRequest) []string {
+	m := map[string]bool{}
+	for s, names := range scopeClaims {
+		if hasScope(scope, s) {
+			for _, name := range names {
+				m[name] = true
+			}
+		}
+	}
+	for name := range requested {
+		m[name] = true
+	}
+
+	var ret []string
+	for name := range m {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ClaimsProvider provides the claims about end-users, returned from the
+// UserInfo endpoint and in ID tokens.
+type ClaimsProvider interface {
+	// GetClaims returns the requested claims of the end-user subject.
+	// Claims not available for the end-user are omitted.
+	GetClaims(subject string, names []string) (map[string]interface{}, error)
+}
+
+// getClaims returns the claims of subject using the server ClaimsProvider,
+// restricted to names. The "sub" claim is always the subject.
+func (s *Server) getClaims(subject string, names []string) (JWTClaims, error) {
+	ret := JWTClaims{}
+	if s.ClaimsProvider != nil && len(names) != 0 {
+		claims, err := s.ClaimsProvider.GetClaims(subject, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if v, ok := claims[name]; ok {
+				ret[name] = v
+			}
+		}
+	}
+	ret["sub"] = subject
+	return ret, nil
+}
+
+// UserInfoRequest is a request for the claims about the end-user that
+// authorized an access token, see:
+// http://openid.net/specs/openid-connect-core-1_0.html#UserInfo
+type UserInfoRequest struct {
+	// Token is the access token sent in the request.
+	Token string
+
+	// AccessGrant is the AccessGrant associated with Token.
+	AccessGrant *AccessGrant
+
+	// Claims are the claims about the end-user. Change if different claims
+	// should be returned.
+	Claims JWTClaims
+}
+
+// HandleUserInfoRequest is the http.HandlerFunc for handling UserInfo
+// requests. The access token is validated as in HandleResourceRequest, and
+// must have the openid scope.
+//
+// The returned claims are requested from the server ClaimsProvider, based on
+// the granted scopes ("profile", "email", "address" and "phone") and the
+// "claims" parameter of the authorization request.
+func (s *Server) HandleUserInfoRequest(w *Response, r *http.Request) *UserInfoRequest {
+	err := r.ParseForm()
+	if err != nil {
+		w.SetError(ErrInvalidRequest)
+		w.InternalError = err
+		return nil
+	}
+
+	// access token is sent in the authorization header, or as a form
+	// parameter, see:
+	// http://openid.net/specs/openid-connect-core-1_0.html#UserInfoRequest
+	ret := &UserInfoRequest{}
+	scheme := "Bearer"
+	if ss := strings.SplitN(r.Header.Get("Authorization"), " ", 2); len(ss) == 2 && (strings.EqualFold(ss[0], "Bearer") || strings.EqualFold(ss[0], DPoPTokenType)) {
+		ret.Token = ss[1]
+		if strings.EqualFold(ss[0], DPoPTokenType) {
+			scheme = DPoPTokenType
+		}
+	} else {
+		ret.Token = r.Form.Get("access_token")
+	}
+	if ret.Token == "" {
+		w.SetError(ErrInvalidToken)
+		w.StatusCode = http.StatusUnauthorized
+		w.InternalError = errors.New("access token not sent")
+		w.Headers.Set("WWW-Authenticate", "Bearer")
+		return nil
+	}
+
+	// must have a valid access token
+	if ret.AccessGrant = s.loadResourceGrant(w, r, ret.Token, scheme); ret.AccessGrant == nil {
+		return nil
+	}
+
+	// must be an openid access token
+	if !hasScope(ret.AccessGrant.Scope, ScopeOpenID) {
+		s.setResourceError(w, scheme, ErrInsufficientScope, errors.New("access token does not have the openid scope"))
+		return nil
+	}
+	if ret.AccessGrant.Subject == "" {
+		s.setResourceError(w, scheme, ErrInvalidToken, errors.New("access token has no subject"))
+		return nil
+	}
+
+	var requested map[string]*ClaimRequest
+	if ret.AccessGrant.Claims != nil {
+		requested = ret.AccessGrant.Claims.UserInfo
+	}
+	ret.Claims, err = s.getClaims(ret.AccessGrant.Subject, claimNames(ret.AccessGrant.Scope, requested))
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return nil
+	}
+
+	return ret
+}
+
+// userInfoSigningAlg returns the registered signing algorithm of client
+// UserInfo responses. Blank if responses are not signed.
+func userInfoSigningAlg(client Client) string {
+	if c, ok := client.(ClientMetadataGetter); ok {
+		if md := c.GetMetadata(); md != nil {
+			return md.UserInfoSignedResponseAlg
+		}
+	}
+	return ""
+}
+
+// FinishUserInfoRequest finalizes the request handled by
+// HandleUserInfoRequest. The claims are returned as a signed JWT to clients
+// registered with a userinfo_signed_response_alg, and as JSON otherwise.
+func (s *Server) FinishUserInfoRequest(w *Response, r *http.Request, ur *UserInfoRequest) {
+	// don't process if is already an error
+	if w.IsError {
+		return
+	}
+
+	alg := userInfoSigningAlg(ur.AccessGrant.Client)
+	if alg == "" {
+		for k, v := range ur.Claims {
+			w.Output[k] = v
+		}
+		return
+	}
+
+	// sign claims, see:
+	// http://openid.net/specs/openid-connect-core-1_0.html#UserInfoResponse
+	key, err := s.signingKey()
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+	if signingAlg(key) != alg {
+		w.SetError(ErrServerError)
+		w.InternalError = errors.New("no signing key for userinfo alg: " + alg)
+		return
+	}
+	claims := JWTClaims{}
+	for k, v := range ur.Claims {
+		claims[k] = v
+	}
+	claims["iss"] = s.Config.Issuer
+	claims["aud"] = ur.AccessGrant.Client.GetID()
+
+	token, err := signClaims(key, claims)
+	if err != nil {
+		w.SetError(ErrServerError)
+		w.InternalError = err
+		return
+	}
+	w.ResponseType = JWT
+	w.Body = token
+}
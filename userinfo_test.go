@@ -0,0 +1,248 @@
+package oauthlib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testClaimsProvider map[string]interface{}
+
+func (p testClaimsProvider) GetClaims(subject string, names []string) (map[string]interface{}, error) {
+	ret := map[string]interface{}{"sub": "other"}
+	for k, v := range p {
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+// testUserClaims are the claims of the test end-user.
+var testUserClaims = testClaimsProvider{
+	"name":         "Jane Doe",
+	"email":        "jane@example.com",
+	"phone_number": "+1 555 0100",
+	"groups":       []string{"admin"},
+}
+
+func TestUserInfo(t *testing.T) {
+	client := &DefaultClient{ID: "5678", RedirectURI: "http://localhost:14000/appauth"}
+	server := newTestServer(t)
+	server.ClaimsProvider = testUserClaims
+	storage := server.Storage.(*MemStorage)
+	storage.Clients[client.ID] = client
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      client,
+		AccessToken: "userinfo",
+		ExpiresIn:   3600,
+		Scope:       "openid,profile",
+		Subject:     "user",
+		Claims:      &ClaimsRequest{UserInfo: map[string]*ClaimRequest{"email": nil}},
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000/userinfo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer userinfo")
+
+	if ur := server.HandleUserInfoRequest(resp, req); ur != nil {
+		server.FinishUserInfoRequest(resp, req, ur)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["sub"]; d != "user" {
+		t.Fatalf("Unexpected subject: %v", d)
+	}
+
+	if d := resp.Output["name"]; d != "Jane Doe" {
+		t.Fatalf("Unexpected name: %v", d)
+	}
+
+	if d := resp.Output["email"]; d != "jane@example.com" {
+		t.Fatalf("Unexpected email: %v", d)
+	}
+
+	for _, name := range []string{"phone_number", "groups"} {
+		if _, ok := resp.Output[name]; ok {
+			t.Fatalf("Claim %s should not be returned", name)
+		}
+	}
+}
+
+func TestUserInfoSigned(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &DefaultClient{
+		ID:          "5678",
+		RedirectURI: "http://localhost:14000/appauth",
+		Metadata:    ClientMetadata{UserInfoSignedResponseAlg: AlgES256},
+	}
+	server := newTestServer(t)
+	server.ClaimsProvider = testUserClaims
+	server.SigningKey = &JSONWebKey{Key: key}
+	storage := server.Storage.(*MemStorage)
+	storage.Clients[client.ID] = client
+	err = storage.SaveAccessGrant(&AccessGrant{
+		Client:      client,
+		AccessToken: "userinfo",
+		ExpiresIn:   3600,
+		Scope:       "openid email",
+		Subject:     "user",
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("GET", "http://localhost:14000/userinfo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer userinfo")
+
+	if ur := server.HandleUserInfoRequest(resp, req); ur != nil {
+		server.FinishUserInfoRequest(resp, req, ur)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if resp.ResponseType != JWT {
+		t.Fatalf("Response should be a jwt")
+	}
+
+	w := httptest.NewRecorder()
+	if err = WriteJSON(w, resp); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/jwt" {
+		t.Fatalf("Unexpected content type: %s", ct)
+	}
+
+	jt, err := parseJWT(w.Body.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = jt.verify(&key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	c := jt.Claims
+	if c.String("sub") != "user" || c.String("email") != "jane@example.com" || c.String("iss") != "http://localhost:14000" || c.String("aud") != "5678" {
+		t.Fatalf("Unexpected claims: %v", c)
+	}
+}
+
+func TestUserInfoInvalid(t *testing.T) {
+	client := &DefaultClient{ID: "5678", RedirectURI: "http://localhost:14000/appauth"}
+	server := newTestServer(t)
+	server.ClaimsProvider = testUserClaims
+	storage := server.Storage.(*MemStorage)
+	storage.Clients[client.ID] = client
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      client,
+		AccessToken: "userinfo",
+		ExpiresIn:   3600,
+		Scope:       "profile",
+		Subject:     "user",
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		token string
+		err   string
+	}{
+		{"userinfo", ErrInsufficientScope.Type},
+		{"unknown", ErrInvalidToken.Type},
+		{"", ErrInvalidToken.Type},
+	}
+
+	for i, tt := range tests {
+		resp := server.NewResponse()
+		req, err := http.NewRequest("GET", "http://localhost:14000/userinfo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Form = url.Values{}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		if ur := server.HandleUserInfoRequest(resp, req); ur != nil {
+			t.Errorf("UserInfo request should have failed (%d)", i)
+			continue
+		}
+
+		if resp.ErrorType != tt.err {
+			t.Errorf("Expected error %q (%d), got: %q", tt.err, i, resp.ErrorType)
+		}
+		if tt.err == ErrInvalidToken.Type && (resp.StatusCode != http.StatusUnauthorized || resp.Headers.Get("WWW-Authenticate") == "") {
+			t.Errorf("Expected WWW-Authenticate challenge (%d), got: %d %q", i, resp.StatusCode, resp.Headers.Get("WWW-Authenticate"))
+		}
+		if tt.err == ErrInsufficientScope.Type && (resp.StatusCode != http.StatusForbidden || !strings.Contains(resp.Headers.Get("WWW-Authenticate"), `error="insufficient_scope"`)) {
+			t.Errorf("Expected insufficient scope challenge (%d), got: %d %q", i, resp.StatusCode, resp.Headers.Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestUserInfoFormToken(t *testing.T) {
+	// clients of backchannel and device grants have no redirect uri
+	client := &DefaultClient{ID: "5678"}
+	server := newTestServer(t)
+	server.ClaimsProvider = testUserClaims
+	storage := server.Storage.(*MemStorage)
+	storage.Clients[client.ID] = client
+	err := storage.SaveAccessGrant(&AccessGrant{
+		Client:      client,
+		AccessToken: "userinfo",
+		ExpiresIn:   3600,
+		Scope:       "openid",
+		Subject:     "user",
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := server.NewResponse()
+
+	req, err := http.NewRequest("POST", "http://localhost:14000/userinfo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Form = url.Values{"access_token": {"userinfo"}}
+	req.PostForm = url.Values{}
+
+	if ur := server.HandleUserInfoRequest(resp, req); ur != nil {
+		server.FinishUserInfoRequest(resp, req, ur)
+	}
+
+	if resp.IsError {
+		t.Fatalf("Should not be an error: %v", resp.InternalError)
+	}
+
+	if d := resp.Output["sub"]; d != "user" {
+		t.Fatalf("Unexpected subject: %v", d)
+	}
+}
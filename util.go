@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
 	"net/url"
 	"strings"
@@ -11,6 +12,16 @@ import (
 
 // WriteJSON encodes the Response to JSON and writes to the http.ResponseWriter
 func WriteJSON(w http.ResponseWriter, rs *Response) error {
+	// output auto-submitting form
+	if rs.ResponseType == REDIRECT && isFormPostResponseMode(rs.ResponseMode) {
+		return WriteFormPost(w, rs)
+	}
+
+	// output front-channel logout page
+	if len(rs.FrontchannelLogoutURIs) != 0 && !rs.IsError {
+		return WriteLogoutPage(w, rs)
+	}
+
 	// Add headers
 	for i, k := range rs.Headers {
 		for _, v := range k {
@@ -26,6 +37,16 @@ func WriteJSON(w http.ResponseWriter, rs *Response) error {
 		}
 		w.Header().Add("Location", u)
 		w.WriteHeader(302)
+	} else if rs.ResponseType == JWT && !rs.IsError {
+		// output signed jwt
+		w.Header().Set("Content-Type", "application/jwt")
+		w.WriteHeader(rs.StatusCode)
+		if _, err := w.Write([]byte(rs.Body)); err != nil {
+			return err
+		}
+	} else if rs.StatusCode == http.StatusNoContent {
+		// no content, don't output body
+		w.WriteHeader(rs.StatusCode)
 	} else {
 		// set content type if the response doesn't already have one associated with it
 		if w.Header().Get("Content-Type") == "" {
@@ -43,6 +64,94 @@ func WriteJSON(w http.ResponseWriter, rs *Response) error {
 	return nil
 }
 
+// formPostTemplate is the auto-submitting HTML form of form_post responses.
+var formPostTemplate = template.Must(template.New("form_post").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Submit This Form</title></head>
+<body onload="javascript:document.forms[0].submit()">
+<form method="post" action="{{.URL}}">
+{{- range $k, $v := .Output}}
+<input type="hidden" name="{{$k}}" value="{{$v}}"/>
+{{- end}}
+<noscript><button type="submit">Continue</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+// WriteFormPost writes the Response as a HTML form, posting the output to
+// the response URL when loaded by the user agent, see:
+// http://openid.net/specs/oauth-v2-form-post-response-mode-1_0.html
+func WriteFormPost(w http.ResponseWriter, rs *Response) error {
+	if rs.ResponseType != REDIRECT {
+		return errors.New("not a redirect response")
+	}
+
+	// Add headers
+	for i, k := range rs.Headers {
+		for _, v := range k {
+			w.Header().Add(i, v)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	output := map[string]string{}
+	for k, v := range rs.Output {
+		output[k] = fmt.Sprint(v)
+	}
+	return formPostTemplate.Execute(w, struct {
+		URL    string
+		Output map[string]string
+	}{rs.URL, output})
+}
+
+// logoutPageTemplate is the HTML page of front-channel logout responses,
+// redirecting when the iframes are loaded.
+var logoutPageTemplate = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Logged Out</title></head>
+<body{{if .URL}} onload="javascript:window.location.replace({{.URL}})"{{end}}>
+{{- range .URIs}}
+<iframe src="{{.}}" style="display:none"></iframe>
+{{- end}}
+{{- if .URL}}
+<noscript><a href="{{.URL}}">Continue</a></noscript>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteLogoutPage writes the Response as a HTML page rendering the
+// front-channel logout URIs as iframes, redirecting to the response URL, if
+// any, when loaded by the user agent, see:
+// http://openid.net/specs/openid-connect-frontchannel-1_0.html#OPLogout
+func WriteLogoutPage(w http.ResponseWriter, rs *Response) error {
+	var u string
+	if rs.ResponseType == REDIRECT {
+		var err error
+		if u, err = rs.GetRedirectURL(); err != nil {
+			return err
+		}
+	}
+
+	// Add headers
+	for i, k := range rs.Headers {
+		for _, v := range k {
+			w.Header().Add(i, v)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	return logoutPageTemplate.Execute(w, struct {
+		URL  string
+		URIs []string
+	}{u, rs.FrontchannelLogoutURIs})
+}
+
 // URIValidationError is the error returned when the passed uri does not pass
 // validation.
 type URIValidationError string
@@ -143,3 +252,10 @@ func firstURI(baseURI string, sep string) string {
 
 	return strings.Split(baseURI, sep)[0]
 }
+
+// splitScope splits a space or comma separated scope into a list of scopes.
+func splitScope(scope string) []string {
+	return strings.FieldsFunc(scope, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}